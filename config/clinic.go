@@ -0,0 +1,35 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	clinicLocation     *time.Location
+	clinicLocationOnce sync.Once
+)
+
+// ClinicTimeZone returns the IANA time zone name of the clinic from CLINIC_TIMEZONE (default Asia/Jakarta).
+func ClinicTimeZone() string {
+	return GetEnv("CLINIC_TIMEZONE", "Asia/Jakarta")
+}
+
+// ClinicLocation returns the clinic's time zone, which defines the calendar day for queues and daily stats.
+func ClinicLocation() *time.Location {
+	clinicLocationOnce.Do(func() {
+		location, err := time.LoadLocation(ClinicTimeZone())
+		if err != nil {
+			log.Fatal("Invalid CLINIC_TIMEZONE:", err)
+		}
+		clinicLocation = location
+	})
+	return clinicLocation
+}
+
+// GeneralPractitionerSpecialty returns the specialty of the doctors patients fall back to when the doctor
+// they were matched with is not working, from GENERAL_PRACTITIONER_SPECIALTY (default General Practitioner).
+func GeneralPractitionerSpecialty() string {
+	return GetEnv("GENERAL_PRACTITIONER_SPECIALTY", "General Practitioner")
+}
@@ -7,13 +7,10 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-
-	"github.com/Om-SEHAT/omsehat-api/models"
 )
 
-var DB *gorm.DB
-
-func ConnectDatabase() {
+// ConnectDatabase opens the Postgres connection, the schema is managed by the migrations package.
+func ConnectDatabase() *gorm.DB {
 	// get database connection string from .env file
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
@@ -21,35 +18,22 @@ func ConnectDatabase() {
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
 
-	// construct DSN
+	// construct DSN, the session time zone follows the clinic so dates are interpreted on the clinic's calendar
 	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Jakarta",
-		dbHost, dbPort, dbUser, dbPassword, dbName,
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=%s",
+		dbHost, dbPort, dbUser, dbPassword, dbName, ClinicLocation().String(),
 	)
 
 	// open connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		// report constraint violations as gorm errors, so repositories can tell duplicates apart
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
 	log.Println("Connected to database successfully")
 
-	// migrate models to databbase
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Session{},
-		&models.Queue{},
-		&models.Doctor{},
-		&models.Message{},
-	)
-
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
-	}
-
-	log.Println("Database migrated successfully")
-
-	// set db to global variable
-	DB = db
+	return db
 }
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthController handles staff logins.
+type AuthController struct {
+	auth *services.AuthService
+}
+
+func NewAuthController(auth *services.AuthService) *AuthController {
+	return &AuthController{auth: auth}
+}
+
+func (ctrl *AuthController) DoctorLogin(c *gin.Context) {
+	var input schemas.LoginInput
+
+	// bind and validate the request body to the input struct
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	doctor, tokens, err := ctrl.auth.DoctorLogin(input)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(401, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Login successful", "doctor": doctor, "tokens": tokens})
+}
+
+func (ctrl *AuthController) AdminLogin(c *gin.Context) {
+	var input schemas.LoginInput
+
+	// bind and validate the request body to the input struct
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	admin, tokens, err := ctrl.auth.AdminLogin(input)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(401, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Login successful", "admin": admin, "tokens": tokens})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	boardWriteTimeout = 10 * time.Second
+	boardPingInterval = 30 * time.Second
+	boardPongTimeout  = 60 * time.Second
+)
+
+var boardUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// the board only exposes ticket numbers, so any origin may subscribe (same as the CORS policy)
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// BoardController streams the queue boards over websockets.
+type BoardController struct {
+	queues  *services.QueueService
+	doctors *services.DoctorService
+}
+
+func NewBoardController(queues *services.QueueService, doctors *services.DoctorService) *BoardController {
+	return &BoardController{queues: queues, doctors: doctors}
+}
+
+// ClinicQueueBoard streams queue events of every doctor over a WebSocket.
+func (ctrl *BoardController) ClinicQueueBoard(c *gin.Context) {
+	ctrl.serveQueueBoard(c, nil)
+}
+
+// DoctorQueueBoard streams queue events of one doctor over a WebSocket.
+func (ctrl *BoardController) DoctorQueueBoard(c *gin.Context) {
+	doctorUUID, err := uuid.Parse(c.Param("doctor_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid doctor ID"})
+		return
+	}
+
+	if ctrl.doctors.GetDoctorByID(doctorUUID.String()) == nil {
+		c.JSON(404, gin.H{"message": "Doctor not found"})
+		return
+	}
+
+	ctrl.serveQueueBoard(c, &doctorUUID)
+}
+
+// serveQueueBoard sends a snapshot of the board, then pushes every queue event until the client disconnects
+func (ctrl *BoardController) serveQueueBoard(c *gin.Context, doctorID *uuid.UUID) {
+	// subscribe before taking the snapshot so no event is missed in between
+	events, cancel := ctrl.queues.Subscribe(doctorID)
+	defer cancel()
+
+	board, err := ctrl.queues.GetQueueBoard(doctorID)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	conn, err := boardUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("Error upgrading queue board connection:", err)
+		return
+	}
+	defer conn.Close()
+
+	// read in the background so pongs and close frames are handled
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(boardPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(boardPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(message interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(boardWriteTimeout))
+		return conn.WriteJSON(message)
+	}
+
+	if err := write(gin.H{"type": "snapshot", "board": board}); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(boardPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := write(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(boardWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -1,13 +1,28 @@
 package controllers
 
 import (
+	"errors"
+
+	"github.com/Om-SEHAT/omsehat-api/middlewares"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/Om-SEHAT/omsehat-api/services"
 	"github.com/Om-SEHAT/omsehat-api/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func DoctorDiagnose(c *gin.Context) {
+// DoctorController handles the doctor directory and diagnoses.
+type DoctorController struct {
+	doctors  *services.DoctorService
+	sessions *services.SessionService
+	queues   *services.QueueService
+}
+
+func NewDoctorController(doctors *services.DoctorService, sessions *services.SessionService, queues *services.QueueService) *DoctorController {
+	return &DoctorController{doctors: doctors, sessions: sessions, queues: queues}
+}
+
+func (ctrl *DoctorController) DoctorDiagnose(c *gin.Context) {
 	sessionId := c.Param("id")
 
 	// Parse the diagnosis from the request body
@@ -20,7 +35,11 @@ func DoctorDiagnose(c *gin.Context) {
 	}
 
 	// Call the service to save the diagnosis
-	if err := services.DoctorDiagnose(sessionId, input.Diagnosis); err != nil {
+	err := ctrl.sessions.DoctorDiagnose(sessionId, middlewares.CurrentSubject(c), input.Diagnosis)
+	if errors.Is(err, services.ErrNotAssignedDoctor) {
+		c.JSON(403, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
@@ -28,9 +47,9 @@ func DoctorDiagnose(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Diagnosis saved successfully"})
 }
 
-func GetAllDoctors(c *gin.Context) {
+func (ctrl *DoctorController) GetAllDoctors(c *gin.Context) {
 	// Fetch all doctors from the database
-	doctors := services.GetAllDoctors()
+	doctors := ctrl.doctors.GetAllDoctors()
 
 	if len(doctors) == 0 {
 		c.JSON(404, gin.H{"message": "No doctors found"})
@@ -41,7 +60,7 @@ func GetAllDoctors(c *gin.Context) {
 	c.JSON(200, gin.H{"doctors": doctors})
 }
 
-func GetDoctorDetails(c *gin.Context) {
+func (ctrl *DoctorController) GetDoctorDetails(c *gin.Context) {
 	doctorID := c.Param("id")
 
 	// Parse doctorID to UUID
@@ -52,24 +71,131 @@ func GetDoctorDetails(c *gin.Context) {
 	}
 
 	// Fetch doctor details from the database
-	doctor := services.GetDoctorByID(doctorID)
+	doctor := ctrl.doctors.GetDoctorByID(doctorID)
 	if doctor == nil {
 		c.JSON(404, gin.H{"error": "Doctor not found"})
 		return
 	}
 
 	// Fetch appointment counts and current queue
-	totalAppointments := services.GetTotalAppointments(id)
-	dailyAppointments := services.GetDailyAppointments(id)
+	totalAppointments := ctrl.queues.GetTotalAppointments(id)
+	dailyAppointments := ctrl.queues.GetDailyAppointments(id)
 
 	// If empty queue, just let currentQueue be nil
-	currentQueue, err := services.GetCurrentQueue(id)
+	currentQueue, err := ctrl.queues.GetCurrentQueue(id)
+
+	// only the doctor and admins see the current patient's session, patients get the ticket number
+	var current any = currentQueue
+	if !middlewares.IsDoctorSelfOrAdmin(c, doctorID) {
+		current = services.QueueTicket(currentQueue)
+	}
 
 	// Respond with aggregated data
 	c.JSON(200, gin.H{
 		"doctor":                     doctor,
 		"appointment_count_all_time": totalAppointments,
 		"appointment_count_daily":    dailyAppointments,
-		"current_queue":              currentQueue, // Current queue ID
+		"current_queue":              current, // Current queue ID
 	})
 }
+
+func (ctrl *DoctorController) SetDoctorPassword(c *gin.Context) {
+	doctorID := c.Param("id")
+
+	var input schemas.SetPasswordInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	if ctrl.doctors.GetDoctorByID(doctorID) == nil {
+		c.JSON(404, gin.H{"message": "Doctor not found"})
+		return
+	}
+
+	if err := ctrl.doctors.SetDoctorPassword(doctorID, input.Password); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Doctor password updated successfully"})
+}
+
+// ListDoctors returns every doctor including deactivated ones, for admins.
+func (ctrl *DoctorController) ListDoctors(c *gin.Context) {
+	doctors, err := ctrl.doctors.ListDoctors()
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"doctors": doctors})
+}
+
+func (ctrl *DoctorController) CreateDoctor(c *gin.Context) {
+	var input schemas.CreateDoctorInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	doctor, err := ctrl.doctors.CreateDoctor(input)
+	if errors.Is(err, services.ErrDoctorEmailTaken) {
+		c.JSON(409, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Doctor created successfully", "doctor": doctor})
+}
+
+func (ctrl *DoctorController) UpdateDoctor(c *gin.Context) {
+	var input schemas.DoctorInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	doctor, err := ctrl.doctors.UpdateDoctor(c.Param("id"), input)
+	if respondDoctorError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Doctor updated successfully", "doctor": doctor})
+}
+
+// DeactivateDoctor soft deletes the doctor, their past queue entries stay intact.
+func (ctrl *DoctorController) DeactivateDoctor(c *gin.Context) {
+	doctor, err := ctrl.doctors.SetDoctorActive(c.Param("id"), false)
+	if respondDoctorError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Doctor deactivated successfully", "doctor": doctor})
+}
+
+func (ctrl *DoctorController) ActivateDoctor(c *gin.Context) {
+	doctor, err := ctrl.doctors.SetDoctorActive(c.Param("id"), true)
+	if respondDoctorError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Doctor activated successfully", "doctor": doctor})
+}
+
+// respondDoctorError writes the response for a failed doctor update, returning false if err is nil.
+func respondDoctorError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrDoctorNotFound):
+		c.JSON(404, gin.H{"message": err.Error()})
+	case errors.Is(err, services.ErrDoctorEmailTaken):
+		c.JSON(409, gin.H{"message": err.Error()})
+	default:
+		c.JSON(500, gin.H{"message": err.Error()})
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OutboxController lets admins inspect and retry outgoing notifications.
+type OutboxController struct {
+	outbox *services.OutboxService
+}
+
+func NewOutboxController(outbox *services.OutboxService) *OutboxController {
+	return &OutboxController{outbox: outbox}
+}
+
+func (ctrl *OutboxController) GetOutboxMessages(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxStatusPending, models.OutboxStatusSent, models.OutboxStatusDead, models.OutboxStatusExpired:
+	default:
+		c.JSON(400, gin.H{"message": "Invalid status"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(400, gin.H{"message": "Invalid limit"})
+		return
+	}
+
+	messages, err := ctrl.outbox.GetMessages(status, limit)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	stats, err := ctrl.outbox.GetStats()
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Outbox messages retrieved", "messages": messages, "stats": stats})
+}
+
+func (ctrl *OutboxController) RetryOutboxMessage(c *gin.Context) {
+	messageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid message ID"})
+		return
+	}
+
+	message, err := ctrl.outbox.RetryMessage(messageUUID)
+	if errors.Is(err, services.ErrOutboxMessageNotFound) {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrOutboxMessageNotRetryable) || errors.Is(err, services.ErrOutboxMessageNotDead) {
+		c.JSON(409, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Outbox message scheduled for retry", "outbox_message": message})
+}
@@ -1,12 +1,27 @@
 package controllers
 
 import (
+	"errors"
+
+	"github.com/Om-SEHAT/omsehat-api/middlewares"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/Om-SEHAT/omsehat-api/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func GetCurrentQueue(c *gin.Context) {
+// QueueController handles the doctors' queues.
+type QueueController struct {
+	queues   *services.QueueService
+	sessions *services.SessionService
+}
+
+func NewQueueController(queues *services.QueueService, sessions *services.SessionService) *QueueController {
+	return &QueueController{queues: queues, sessions: sessions}
+}
+
+func (ctrl *QueueController) GetCurrentQueue(c *gin.Context) {
 	// Get the current queue for the user
 	doctorID := c.Param("doctor_id")
 
@@ -19,7 +34,7 @@ func GetCurrentQueue(c *gin.Context) {
 	}
 
 	// get the current queue
-	queue, err := services.GetCurrentQueue(doctorUUID)
+	queue, err := ctrl.queues.GetCurrentQueue(doctorUUID)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
@@ -30,9 +45,123 @@ func GetCurrentQueue(c *gin.Context) {
 		c.JSON(404, gin.H{"message": "No queue found"})
 		return
 	}
+	ctrl.queues.SetEstimatedWait(queue)
+
+	// the remaining tickets of the day with their estimated wait
+	waiting, err := ctrl.queues.GetWaitingQueue(doctorUUID)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// only the doctor and admins see who the tickets belong to, patients get the numbers and waits
+	if !middlewares.IsDoctorSelfOrAdmin(c, doctorID) {
+		tickets := make([]schemas.QueueTicket, 0, len(waiting))
+		for i := range waiting {
+			tickets = append(tickets, *services.QueueTicket(&waiting[i]))
+		}
+		c.JSON(200, gin.H{
+			"queue":   services.QueueTicket(queue),
+			"waiting": tickets,
+		})
+		return
+	}
 
 	// return the queue
 	c.JSON(200, gin.H{
-		"queue": queue,
+		"queue":   queue,
+		"waiting": waiting,
 	})
 }
+
+func (ctrl *QueueController) CallNextQueue(c *gin.Context) {
+	doctorUUID, err := uuid.Parse(c.Param("doctor_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid doctor ID"})
+		return
+	}
+
+	queue, err := ctrl.queues.CallNextQueue(doctorUUID)
+	if errors.Is(err, services.ErrQueueEmpty) {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Next patient called", "queue": queue})
+}
+
+func (ctrl *QueueController) RecallQueueEntry(c *gin.Context) {
+	ctrl.updateQueueStatus(c, models.QueueStatusCalled, "Patient called again")
+}
+
+func (ctrl *QueueController) StartQueueEntry(c *gin.Context) {
+	ctrl.updateQueueStatus(c, models.QueueStatusInConsultation, "Consultation started")
+}
+
+func (ctrl *QueueController) SkipQueueEntry(c *gin.Context) {
+	ctrl.updateQueueStatus(c, models.QueueStatusSkipped, "Patient marked as no-show")
+}
+
+func (ctrl *QueueController) CompleteQueueEntry(c *gin.Context) {
+	ctrl.updateQueueStatus(c, models.QueueStatusDone, "Consultation completed")
+}
+
+func (ctrl *QueueController) CancelQueueEntry(c *gin.Context) {
+	queueUUID, err := uuid.Parse(c.Param("queue_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid queue ID"})
+		return
+	}
+
+	// patients can only cancel their own tickets
+	if middlewares.CurrentRole(c) == models.RolePatient {
+		queue := ctrl.queues.GetQueueByID(queueUUID)
+		if queue == nil {
+			c.JSON(404, gin.H{"message": services.ErrQueueNotFound.Error()})
+			return
+		}
+
+		ownerID, err := ctrl.sessions.GetSessionOwnerID(queue.SessionID)
+		if err != nil || ownerID != middlewares.CurrentSubject(c) {
+			c.JSON(403, gin.H{"message": "You are not allowed to cancel this queue entry"})
+			return
+		}
+	} else if !middlewares.IsDoctorSelfOrAdmin(c, c.Param("doctor_id")) {
+		c.JSON(403, gin.H{"message": "You are not allowed to access this doctor"})
+		return
+	}
+
+	ctrl.updateQueueStatus(c, models.QueueStatusCancelled, "Queue entry cancelled")
+}
+
+// updateQueueStatus moves the queue entry in the URL to the given status and writes the response
+func (ctrl *QueueController) updateQueueStatus(c *gin.Context, status string, message string) {
+	doctorUUID, err := uuid.Parse(c.Param("doctor_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid doctor ID"})
+		return
+	}
+
+	queueUUID, err := uuid.Parse(c.Param("queue_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid queue ID"})
+		return
+	}
+
+	queue, err := ctrl.queues.TransitionQueue(doctorUUID, queueUUID, status)
+	if errors.Is(err, services.ErrQueueNotFound) {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrInvalidQueueTransition) {
+		c.JSON(409, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": message, "queue": queue})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleController lets admins manage the doctors' shifts, schedule exceptions and leave.
+type ScheduleController struct {
+	availability *services.AvailabilityService
+}
+
+func NewScheduleController(availability *services.AvailabilityService) *ScheduleController {
+	return &ScheduleController{availability: availability}
+}
+
+func (ctrl *ScheduleController) GetSchedule(c *gin.Context) {
+	schedule, err := ctrl.availability.GetSchedule(c.Param("id"))
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"schedule": schedule})
+}
+
+func (ctrl *ScheduleController) SetShifts(c *gin.Context) {
+	var input schemas.SetShiftsInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	shifts, err := ctrl.availability.SetShifts(c.Param("id"), input)
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Shifts updated successfully", "shifts": shifts})
+}
+
+func (ctrl *ScheduleController) AddException(c *gin.Context) {
+	var input schemas.ScheduleExceptionInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	exception, err := ctrl.availability.AddException(c.Param("id"), input)
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Schedule exception added successfully", "exception": exception})
+}
+
+func (ctrl *ScheduleController) RemoveException(c *gin.Context) {
+	err := ctrl.availability.RemoveException(c.Param("id"), c.Param("exception_id"))
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Schedule exception removed successfully"})
+}
+
+func (ctrl *ScheduleController) AddLeave(c *gin.Context) {
+	var input schemas.LeaveInput
+
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	leave, err := ctrl.availability.AddLeave(c.Param("id"), input)
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Leave added successfully", "leave": leave})
+}
+
+func (ctrl *ScheduleController) RemoveLeave(c *gin.Context) {
+	err := ctrl.availability.RemoveLeave(c.Param("id"), c.Param("leave_id"))
+	if respondScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Leave removed successfully"})
+}
+
+// respondScheduleError writes the response for a failed schedule request, returning false if err is nil.
+func respondScheduleError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrDoctorNotFound), errors.Is(err, services.ErrScheduleEntryNotFound):
+		c.JSON(404, gin.H{"message": err.Error()})
+	case errors.Is(err, services.ErrInvalidSchedule):
+		c.JSON(400, gin.H{"message": err.Error()})
+	default:
+		c.JSON(500, gin.H{"message": err.Error()})
+	}
+	return true
+}
@@ -1,30 +1,32 @@
 package controllers
 
 import (
-	"log"
-	"os"
+	"errors"
 
-	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/models"
 	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/Om-SEHAT/omsehat-api/services"
 	"github.com/Om-SEHAT/omsehat-api/utils"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func GenerateSessionResponse(c *gin.Context) {
-	session_id := c.Param("id")
+// llmOutputErrorMessage is shown when the LLM could not give a usable answer, sending the message again usually works
+const llmOutputErrorMessage = "OmSapa could not answer right now, please send your message again."
 
-	// check if session_id exists in the database
-	var existingSession models.Session
+// SessionController handles the chat between patients and the LLM.
+type SessionController struct {
+	sessions *services.SessionService
+}
 
-	err := config.DB.Preload("User").
-		Preload("Messages", func(db *gorm.DB) *gorm.DB {
-			return db.Order("created_at ASC") // Order messages by created_at in ascending order (for earlier messages first)
-		}).
-		Where("id = ?", session_id).First(&existingSession).Error
+func NewSessionController(sessions *services.SessionService) *SessionController {
+	return &SessionController{sessions: sessions}
+}
 
+func (ctrl *SessionController) GenerateSessionResponse(c *gin.Context) {
+	session_id := c.Param("id")
+
+	// check if session_id exists in the database
+	existingSession, err := ctrl.sessions.GetSessionData(session_id)
 	if err != nil {
 		c.JSON(404, gin.H{"message": "Session not found"})
 		return
@@ -38,74 +40,25 @@ func GenerateSessionResponse(c *gin.Context) {
 		return // The response has already been sent in the utility function
 	}
 
-	// get the message reply from LLM
-	reply, err := services.GetLLMResponse(input.NewMessage, &existingSession)
-	if err != nil {
+	// get the structured reply from LLM
+	LLMResponse, err := ctrl.sessions.GetLLMResponse(input.NewMessage, &existingSession)
+	if routing := services.RoutingForError(err); routing != nil {
+		c.JSON(409, gin.H{"message": err.Error(), "routing": routing})
+		return
+	} else if isLLMOutputError(err) {
+		c.JSON(503, gin.H{"message": llmOutputErrorMessage, "retryable": true})
+		return
+	} else if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
-	// parse the LLM response
-	var LLMResponse schemas.LLMResponse
-	LLMResponse, err = services.ParseJSON(reply)
-
-	// queue var
-	var queue *models.Queue = nil
-	var currentQueue *models.Queue
-
-	// from the LLM response determine the next action
-	log.Println("LLM Response Next Action:", LLMResponse.NextAction)
-	log.Println("-----------------------------------")
-	log.Println("LLM Response Doctor ID:", LLMResponse.DoctorID)
-	log.Println("-----------------------------------")
-	log.Println("LLM Response:", LLMResponse.Reply)
-	if next_action := LLMResponse.NextAction; next_action == "CONTINUE_CHAT" {
-		// just continue
-
-	} else if next_action == "APPOINTMENT" {
-		// create queue
-		queue, err = services.GenerateQueue(session_id, LLMResponse.DoctorID)
-		if err != nil {
-			c.JSON(500, gin.H{"message": err.Error()})
-			return
-		}
-
-		// preload queue's doctor
-		err = config.DB.Preload("Doctor").Where("id = ?", queue.ID).First(&queue).Error
-		if err != nil {
-			c.JSON(500, gin.H{"message": err.Error()})
-			return
-		}
-
-		// send email to the user
-		currentQueue, err = services.GetCurrentQueue(queue.DoctorID)
-		if err != nil {
-			c.JSON(500, gin.H{"message": err.Error()})
-			return
-		}
-
-		_, err = services.SendQueueEmail(existingSession.User.Email, queue.Number, currentQueue.Number, os.Getenv("EMAIL_TOKEN"), queue.Doctor)
-		if err != nil {
-			log.Println("Error sending email:", err)
-		}
-
-		// update the session's prediagnosis
-		existingSession.Prediagnosis = LLMResponse.PreDiagnosis
-
-		err = config.DB.Save(&existingSession).Error
-		if err != nil {
-			c.JSON(500, gin.H{"message": err.Error()})
-			return
-		}
-
-	} else {
-		c.JSON(500, gin.H{"message": "Invalid next action"})
+	// act on the next action and save the chat history
+	queue, currentQueue, err := ctrl.sessions.ApplyLLMResponse(&existingSession, input.NewMessage, LLMResponse)
+	if routing := services.RoutingForError(err); routing != nil {
+		c.JSON(409, gin.H{"message": err.Error(), "routing": routing})
 		return
-	}
-
-	// update the chat history with the new message and LLM response
-	err = services.UpdateChatHistory(session_id, input.NewMessage, LLMResponse.Reply)
-	if err != nil {
+	} else if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
@@ -116,17 +69,87 @@ func GenerateSessionResponse(c *gin.Context) {
 		"next_action":   LLMResponse.NextAction,
 		"reply":         LLMResponse.Reply,
 		"session_id":    session_id,
-		"queue":         queue,        // queue is nil if next_action is not APPOINTMENT
-		"current_queue": currentQueue, // currentQueue is nil if next_action is not APPOINTMENT
+		"queue":         queue,                           // queue is nil if next_action is not APPOINTMENT
+		"current_queue": currentQueue,                    // currentQueue is nil if next_action is not APPOINTMENT
+		"routing":       services.DescribeRouting(queue), // routing is nil unless the ticket went to another doctor or day
 	})
 }
 
-func GetActiveSession(c *gin.Context) {
+// StreamSessionResponse works like GenerateSessionResponse but streams the reply over Server-Sent Events.
+// It emits "reply" events with each new piece of the reply text, then a single "done" event once the
+// response has been persisted, or an "error" event if anything fails after the stream has started.
+func (ctrl *SessionController) StreamSessionResponse(c *gin.Context) {
+	session_id := c.Param("id")
+
+	// check if session_id exists in the database
+	existingSession, err := ctrl.sessions.GetSessionData(session_id)
+	if err != nil {
+		c.JSON(404, gin.H{"message": "Session not found"})
+		return
+	}
+
+	// get the new message from user
+	var input schemas.SessionChatInput
+
+	// bind and validate the input
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+
+	// stream the reply text as the LLM generates it
+	LLMResponse, err := ctrl.sessions.StreamLLMResponse(c.Request.Context(), input.NewMessage, &existingSession, func(delta string) {
+		c.SSEvent("reply", gin.H{"delta": delta})
+		c.Writer.Flush()
+	})
+	if routing := services.RoutingForError(err); routing != nil {
+		c.SSEvent("error", gin.H{"message": err.Error(), "routing": routing})
+		return
+	} else if isLLMOutputError(err) {
+		c.SSEvent("error", gin.H{"message": llmOutputErrorMessage, "retryable": true})
+		return
+	} else if err != nil {
+		c.SSEvent("error", gin.H{"message": err.Error()})
+		return
+	}
+
+	// act on the next action and save the chat history
+	queue, currentQueue, err := ctrl.sessions.ApplyLLMResponse(&existingSession, input.NewMessage, LLMResponse)
+	if routing := services.RoutingForError(err); routing != nil {
+		c.SSEvent("error", gin.H{"message": err.Error(), "routing": routing})
+		return
+	} else if err != nil {
+		c.SSEvent("error", gin.H{"message": err.Error()})
+		return
+	}
+
+	c.SSEvent("done", gin.H{
+		"message":       "Chat history updated successfully",
+		"next_action":   LLMResponse.NextAction,
+		"reply":         LLMResponse.Reply,
+		"session_id":    session_id,
+		"queue":         queue,                           // queue is nil if next_action is not APPOINTMENT
+		"current_queue": currentQueue,                    // currentQueue is nil if next_action is not APPOINTMENT
+		"routing":       services.DescribeRouting(queue), // routing is nil unless the ticket went to another doctor or day
+	})
+}
+
+// isLLMOutputError reports whether the LLM kept answering with output that could not be used
+func isLLMOutputError(err error) bool {
+	var outputErr *services.LLMOutputError
+	return errors.As(err, &outputErr)
+}
+
+func (ctrl *SessionController) GetActiveSession(c *gin.Context) {
 	session_id := c.Param("id")
 
 	var session models.Session
 
-	session, err := services.GetSessionData(session_id)
+	session, err := ctrl.sessions.GetSessionData(session_id)
 	if err != nil {
 		c.JSON(404, gin.H{"message": "Session not found"})
 		return
@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/Om-SEHAT/omsehat-api/schemas"
@@ -14,7 +17,19 @@ import (
 
 var validate = validator.New()
 
-func RegisterUser(c *gin.Context) {
+// UserController handles patient registration, OTP verification and patient details.
+type UserController struct {
+	users    *services.UserService
+	auth     *services.AuthService
+	sessions *services.SessionService
+	queues   *services.QueueService
+}
+
+func NewUserController(users *services.UserService, auth *services.AuthService, sessions *services.SessionService, queues *services.QueueService) *UserController {
+	return &UserController{users: users, auth: auth, sessions: sessions, queues: queues}
+}
+
+func (ctrl *UserController) RegisterUser(c *gin.Context) {
 	var input schemas.RegisterUserInput
 
 	// bind and validate the request body to the input struct
@@ -23,9 +38,14 @@ func RegisterUser(c *gin.Context) {
 	}
 
 	// Call the service to register the user
-	user, err := services.RegisterUser(input)
+	user, err := ctrl.users.RegisterUser(input)
 
-	if err != nil {
+	if respondOTPError(c, err) {
+		return
+	} else if errors.Is(err, services.ErrPhoneRequired) || errors.Is(err, services.ErrChannelUnavailable) {
+		c.JSON(400, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
@@ -38,10 +58,12 @@ func RegisterUser(c *gin.Context) {
 			"name":  user.Name,
 			"email": user.Email,
 		},
+		"otp_channel":    user.OTPChannel,
+		"otp_expires_in": int(services.OTPExpiresIn().Seconds()),
 	})
 }
 
-func VerifyOTP(c *gin.Context) {
+func (ctrl *UserController) VerifyOTP(c *gin.Context) {
 	var input schemas.OTPInput
 
 	// bind and validate the request body to the input struct
@@ -50,17 +72,142 @@ func VerifyOTP(c *gin.Context) {
 	}
 
 	// Call the service to verify the OTP
-	session, err := services.ValidateOTP(input)
+	session, tokens, err := ctrl.users.ValidateOTP(input)
+
+	if respondOTPError(c, err) {
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "OTP verified successfully", "session": session, "tokens": tokens})
+}
+
+func (ctrl *UserController) RefreshToken(c *gin.Context) {
+	var input schemas.RefreshTokenInput
+
+	// bind and validate the request body to the input struct
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // The response has already been sent in the utility function
+	}
+
+	// exchange the refresh token for a new token pair
+	tokens, err := ctrl.auth.RefreshTokens(input.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		c.JSON(401, gin.H{"message": "Invalid or expired refresh token"})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Token refreshed successfully", "tokens": tokens})
+}
+
+// ChangeContact switches the channel the user is reached on, sending a code first if it uses a new phone number
+func (ctrl *UserController) ChangeContact(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	var input schemas.ContactChangeInput
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // the response has already been sent in the utility function
+	}
+
+	user, pending, err := ctrl.users.RequestContactChange(id, input)
+	if respondOTPError(c, err) {
+		return
+	} else if errors.Is(err, services.ErrPhoneRequired) || errors.Is(err, services.ErrChannelUnavailable) {
+		c.JSON(400, gin.H{"message": err.Error()})
+		return
+	} else if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
 
+	if pending {
+		c.JSON(202, gin.H{
+			"message":        "A verification code was sent to the new phone number",
+			"otp_channel":    user.PendingChannel,
+			"otp_expires_in": int(services.OTPExpiresIn().Seconds()),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Contact preference updated successfully", "preferred_channel": user.PreferredChannel})
+}
+
+// VerifyContact confirms a phone number change with the code sent to the new number
+func (ctrl *UserController) VerifyContact(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
+		c.JSON(400, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	var input schemas.ContactVerifyInput
+	if valid, _ := utils.BindAndValidate(c, &input); !valid {
+		return // the response has already been sent in the utility function
+	}
+
+	user, err := ctrl.users.VerifyContactChange(id, input.OTP)
+	if respondOTPError(c, err) {
+		return
+	} else if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(404, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "OTP verified successfully", "session": session})
+	c.JSON(200, gin.H{
+		"message":           "Phone number verified successfully",
+		"phone":             user.Phone,
+		"preferred_channel": user.PreferredChannel,
+	})
+}
+
+// respondOTPError maps OTP errors to their status code and error code, returning false if err is not an OTP error.
+func respondOTPError(c *gin.Context, err error) bool {
+	var status int
+	var code string
+
+	switch {
+	case errors.Is(err, services.ErrOTPInvalid):
+		status, code = 401, "OTP_INVALID"
+	case errors.Is(err, services.ErrOTPExpired):
+		status, code = 401, "OTP_EXPIRED"
+	case errors.Is(err, services.ErrOTPLocked):
+		status, code = 423, "OTP_LOCKED"
+	case errors.Is(err, services.ErrOTPCooldown):
+		status, code = 429, "OTP_RESEND_COOLDOWN"
+	default:
+		return false
+	}
+
+	response := gin.H{"message": err.Error(), "code": code}
+
+	// tell the client how long to wait before trying again
+	var retryErr *services.OTPRetryError
+	if errors.As(err, &retryErr) {
+		retryAfter := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		response["retry_after"] = retryAfter
+	}
+
+	c.JSON(status, response)
+	return true
 }
 
-func GetUserDetails(c *gin.Context) {
+func (ctrl *UserController) GetUserDetails(c *gin.Context) {
 	userID := c.Param("id")
 
 	// Parse userID to UUID
@@ -71,14 +218,14 @@ func GetUserDetails(c *gin.Context) {
 	}
 
 	// Fetch user details
-	user := services.GetUserByID(id)
+	user := ctrl.users.GetUserByID(id)
 	if user == nil {
 		c.JSON(404, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Fetch sessions for the user
-	sessions := services.GetSessionsByUserID(id)
+	sessions := ctrl.sessions.GetSessionsByUserID(id)
 	if sessions == nil {
 		c.JSON(404, gin.H{"error": "No sessions found for the user"})
 		return
@@ -106,7 +253,8 @@ func GetUserDetails(c *gin.Context) {
 			latestTimestamp = session.CreatedAt
 
 			// Fetch queue for the current session
-			queue := services.GetQueueBySessionID(session.ID)
+			queue := ctrl.queues.GetQueueBySessionID(session.ID)
+			ctrl.queues.SetEstimatedWait(queue)
 
 			currentSession = gin.H{
 				"queue":            queue,
@@ -0,0 +1,8 @@
+// Package emails holds the notification templates, one directory per language.
+// Every template has an html and a plain text email variant (_mail) and a short text variant (_short) for SMS and WhatsApp.
+package emails
+
+import "embed"
+
+//go:embed */*.html */*.txt
+var Templates embed.FS
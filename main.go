@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	_ "time/tzdata" // embed the time zone database, the runtime image has none
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -10,6 +12,11 @@ import (
 
 	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/controllers"
+	"github.com/Om-SEHAT/omsehat-api/middlewares"
+	"github.com/Om-SEHAT/omsehat-api/migrations"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/services"
 )
 
 func main() {
@@ -26,32 +33,144 @@ func main() {
 	}
 
 	// Connect to the database
-	config.ConnectDatabase()
+	db := config.ConnectDatabase()
+
+	// "migrate up|down|status" manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// refuse to run against a schema that is missing migrations
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		log.Fatal("Failed to check database migrations:", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Database schema is %d migration(s) behind, run \"migrate up\" first\n", len(pending))
+	}
+
+	store := repositories.NewGormStore(db)
+
+	// Initialize the notifier used for OTP and queue messages
+	notifier, err := services.NewNotifierFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize notifier:", err)
+	}
+
+	// Initialize the LLM provider used by the chat sessions
+	llmProvider, err := services.NewLLMProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize LLM provider:", err)
+	}
+
+	// wire the services
+	outboxService := services.NewOutboxService(store, notifier)
+	authService := services.NewAuthService(store)
+	userService := services.NewUserService(store, outboxService)
+	doctorService := services.NewDoctorService(store)
+	availabilityService := services.NewAvailabilityService(store)
+	queueService := services.NewQueueService(store, services.NewInProcessQueueHub(), outboxService, availabilityService)
+	sessionService := services.NewSessionService(store, llmProvider, queueService, availabilityService, outboxService)
+	reminderService := services.NewQueueReminderService(store, queueService, outboxService)
+	idempotencyService := services.NewIdempotencyService(store)
+
+	// Create the bootstrap admin account if configured
+	authService.EnsureDefaultAdmin()
+
+	// Deliver queued notifications in the background
+	outboxService.StartWorker(context.Background())
+
+	// Remind waiting patients when their turn is near
+	reminderService.StartScheduler(context.Background())
+
+	// Forget stored idempotent responses once they expire
+	idempotencyService.StartCleanup(context.Background())
+
+	authController := controllers.NewAuthController(authService)
+	userController := controllers.NewUserController(userService, authService, sessionService, queueService)
+	sessionController := controllers.NewSessionController(sessionService)
+	doctorController := controllers.NewDoctorController(doctorService, sessionService, queueService)
+	queueController := controllers.NewQueueController(queueService, sessionService)
+	boardController := controllers.NewBoardController(queueService, doctorService)
+	scheduleController := controllers.NewScheduleController(availabilityService)
+	outboxController := controllers.NewOutboxController(outboxService)
 
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowAllOrigins = true
+	corsConfig.AddAllowHeaders("Idempotency-Key")
+	corsConfig.AddExposeHeaders("Idempotent-Replayed")
 
 	r := gin.Default()
 	r.Use(cors.New(corsConfig))
 
 	// register routes
-	r.POST("/register", controllers.RegisterUser)
-	r.POST("/verify-otp", controllers.VerifyOTP)
+	r.POST("/register", userController.RegisterUser)
+	r.POST("/verify-otp", userController.VerifyOTP)
+	r.POST("/refresh-token", userController.RefreshToken)
+
+	// staff login routes
+	r.POST("/doctor/login", authController.DoctorLogin)
+	r.POST("/admin/login", authController.AdminLogin)
+
+	// routes below require a valid access token, of a doctor who is still active if it is a doctor's
+	auth := r.Group("/", middlewares.RequireAuth(), middlewares.RequireActiveDoctor(doctorService))
+	doctorOnly := middlewares.RequireRole(models.RoleDoctor)
+	adminOnly := middlewares.RequireRole(models.RoleAdmin)
+	staffOnly := middlewares.RequireRole(models.RoleDoctor, models.RoleAdmin)
+	sessionOwner := middlewares.RequireSessionOwner(sessionService, "id")
+	idempotent := middlewares.Idempotency(idempotencyService)
 
 	// session routes
-	r.GET("/session/:id", controllers.GetActiveSession)
-	r.POST("/session/:id", controllers.GenerateSessionResponse)
-	r.POST("/session/:id/diagnose", controllers.DoctorDiagnose)
+	auth.GET("/session/:id", sessionOwner, sessionController.GetActiveSession)
+	auth.POST("/session/:id", sessionOwner, idempotent, sessionController.GenerateSessionResponse)
+	auth.POST("/session/:id/stream", sessionOwner, sessionController.StreamSessionResponse)
+	auth.POST("/session/:id/diagnose", doctorOnly, doctorController.DoctorDiagnose)
+
+	// queue routes, patients reading a queue only get the ticket number and status
+	doctorSelf := middlewares.RequireDoctorSelf("doctor_id")
+	auth.GET("/queue/:doctor_id", queueController.GetCurrentQueue)
+	auth.POST("/queue/:doctor_id/call-next", staffOnly, doctorSelf, queueController.CallNextQueue)
+	auth.POST("/queue/:doctor_id/entries/:queue_id/recall", staffOnly, doctorSelf, queueController.RecallQueueEntry)
+	auth.POST("/queue/:doctor_id/entries/:queue_id/start", staffOnly, doctorSelf, queueController.StartQueueEntry)
+	auth.POST("/queue/:doctor_id/entries/:queue_id/skip", staffOnly, doctorSelf, queueController.SkipQueueEntry)
+	auth.POST("/queue/:doctor_id/entries/:queue_id/complete", staffOnly, doctorSelf, queueController.CompleteQueueEntry)
+	auth.POST("/queue/:doctor_id/entries/:queue_id/cancel", queueController.CancelQueueEntry)
+
+	// live queue board routes, only ticket numbers are exposed so they are public
+	r.GET("/queue/board/ws", boardController.ClinicQueueBoard)
+	r.GET("/queue/:doctor_id/ws", boardController.DoctorQueueBoard)
+
+	// doctor routes, the current patient's session is only shown to the doctor and admins
+	r.GET("/doctors", doctorController.GetAllDoctors)
+	auth.GET("/doctor/:id", doctorController.GetDoctorDetails)
+	auth.PUT("/doctor/:id/password", adminOnly, doctorController.SetDoctorPassword)
+
+	// doctor management routes
+	auth.GET("/admin/doctors", adminOnly, doctorController.ListDoctors)
+	auth.POST("/admin/doctors", adminOnly, doctorController.CreateDoctor)
+	auth.PUT("/admin/doctors/:id", adminOnly, doctorController.UpdateDoctor)
+	auth.DELETE("/admin/doctors/:id", adminOnly, doctorController.DeactivateDoctor)
+	auth.POST("/admin/doctors/:id/activate", adminOnly, doctorController.ActivateDoctor)
 
-	// queue routes
-	r.GET("/queue/:doctor_id", controllers.GetCurrentQueue)
+	// doctor schedule routes
+	auth.GET("/admin/doctors/:id/schedule", adminOnly, scheduleController.GetSchedule)
+	auth.PUT("/admin/doctors/:id/shifts", adminOnly, scheduleController.SetShifts)
+	auth.POST("/admin/doctors/:id/exceptions", adminOnly, scheduleController.AddException)
+	auth.DELETE("/admin/doctors/:id/exceptions/:exception_id", adminOnly, scheduleController.RemoveException)
+	auth.POST("/admin/doctors/:id/leaves", adminOnly, scheduleController.AddLeave)
+	auth.DELETE("/admin/doctors/:id/leaves/:leave_id", adminOnly, scheduleController.RemoveLeave)
 
-	// doctor routes
-	r.GET("/doctors", controllers.GetAllDoctors)
-	r.GET("/doctor/:id", controllers.GetDoctorDetails)
+	// notification outbox routes
+	auth.GET("/admin/outbox", adminOnly, outboxController.GetOutboxMessages)
+	auth.POST("/admin/outbox/:id/retry", adminOnly, outboxController.RetryOutboxMessage)
 
 	// user routes
-	r.GET("/user/:id", controllers.GetUserDetails)
+	auth.GET("/user/:id", middlewares.RequireSelf("id"), userController.GetUserDetails)
+	auth.PUT("/user/:id/contact", middlewares.RequireSelf("id"), userController.ChangeContact)
+	auth.POST("/user/:id/contact/verify", middlewares.RequireSelf("id"), userController.VerifyContact)
 
 	// test routes
 	r.GET("/ping", func(c *gin.Context) {
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	authSubjectKey = "auth_subject"
+	authRoleKey    = "auth_role"
+)
+
+// RequireAuth makes sure the request carries a valid access token and stores its claims in the context.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || tokenString == "" {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Missing or malformed authorization header"})
+			return
+		}
+
+		claims, err := services.ParseToken(tokenString, services.TokenTypeAccess)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Invalid or expired token"})
+			return
+		}
+
+		subject, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Invalid or expired token"})
+			return
+		}
+
+		c.Set(authSubjectKey, subject)
+		c.Set(authRoleKey, claims.Role)
+		c.Next()
+	}
+}
+
+// RequireActiveDoctor rejects the tokens of deactivated doctors. Access tokens outlive a deactivation, so the
+// doctor is looked up again on every request.
+func RequireActiveDoctor(doctors *services.DoctorService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentRole(c) == models.RoleDoctor {
+			doctor := doctors.GetDoctorByID(CurrentSubject(c).String())
+			if doctor == nil || !doctor.IsActive() {
+				c.AbortWithStatusJSON(401, gin.H{"message": "Invalid or expired token"})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// CurrentSubject returns the authenticated account ID set by RequireAuth.
+func CurrentSubject(c *gin.Context) uuid.UUID {
+	subject, _ := c.Get(authSubjectKey)
+	id, _ := subject.(uuid.UUID)
+	return id
+}
+
+// CurrentRole returns the authenticated account role set by RequireAuth.
+func CurrentRole(c *gin.Context) string {
+	return c.GetString(authRoleKey)
+}
+
+// RequireSelf only lets patients through when the URL param is their own user ID.
+func RequireSelf(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"message": "Invalid user ID"})
+			return
+		}
+
+		if CurrentRole(c) != models.RolePatient || CurrentSubject(c) != id {
+			c.AbortWithStatusJSON(403, gin.H{"message": "You are not allowed to access this user"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSessionOwner only lets patients through when the session in the URL param belongs to them.
+func RequireSessionOwner(sessions *services.SessionService, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"message": "Invalid session ID"})
+			return
+		}
+
+		ownerID, err := sessions.GetSessionOwnerID(sessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(404, gin.H{"message": "Session not found"})
+			return
+		}
+
+		if CurrentRole(c) != models.RolePatient || CurrentSubject(c) != ownerID {
+			c.AbortWithStatusJSON(403, gin.H{"message": "You are not allowed to access this session"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole only lets through accounts whose role is one of the given roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, CurrentRole(c)) {
+			c.AbortWithStatusJSON(403, gin.H{"message": "You are not allowed to perform this action"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireDoctorSelf only lets through the doctor whose ID is in the URL param, or an admin.
+func RequireDoctorSelf(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := uuid.Parse(c.Param(param)); err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"message": "Invalid doctor ID"})
+			return
+		}
+
+		if !IsDoctorSelfOrAdmin(c, c.Param(param)) {
+			c.AbortWithStatusJSON(403, gin.H{"message": "You are not allowed to access this doctor"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IsDoctorSelfOrAdmin reports whether the authenticated account is the given doctor or an admin.
+func IsDoctorSelfOrAdmin(c *gin.Context, doctorID string) bool {
+	switch CurrentRole(c) {
+	case models.RoleAdmin:
+		return true
+	case models.RoleDoctor:
+		id, err := uuid.Parse(doctorID)
+		return err == nil && CurrentSubject(c) == id
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/Om-SEHAT/omsehat-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// responseRecorder keeps a copy of the response body while it is written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// requestFingerprint hashes what identifies a request, so a key reused for a different request can be detected
+func requestFingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotency replays the stored response when an authenticated request is retried with the same
+// Idempotency-Key header, instead of processing it again. Requests without the header pass through.
+// Failed requests (5xx) release the key so they can be retried.
+func Idempotency(keys *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(400, gin.H{"message": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"message": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := keys.Begin(CurrentSubject(c), key, requestFingerprint(c.Request.Method, c.Request.URL.Path, body))
+		if errors.Is(err, services.ErrIdempotencyKeyReused) {
+			c.AbortWithStatusJSON(422, gin.H{"message": err.Error()})
+			return
+		} else if errors.Is(err, services.ErrIdempotencyKeyInProgress) {
+			c.AbortWithStatusJSON(409, gin.H{"message": err.Error()})
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
+			return
+		}
+
+		if replay {
+			c.Header(idempotentReplayedHeader, "true")
+			c.Data(record.ResponseStatus, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if status := recorder.Status(); status >= 500 {
+			err = keys.Release(record)
+		} else {
+			err = keys.Complete(record, status, recorder.body.String())
+		}
+		if err != nil {
+			log.Printf("Error storing idempotent response: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS outbox_messages;
+DROP TABLE IF EXISTS queue_counters;
+DROP TABLE IF EXISTS queues;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS admins;
+DROP TABLE IF EXISTS doctors;
+DROP TABLE IF EXISTS users;
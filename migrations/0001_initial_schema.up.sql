@@ -0,0 +1,194 @@
+-- Baseline schema. Every statement is guarded so databases created by AutoMigrate are adopted as they are,
+-- columns added since the first release are added to tables that already exist.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id                          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name                        varchar(100) NOT NULL,
+    email                       varchar(100) NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    nationality                 varchar(100) NOT NULL,
+    dob                         date NOT NULL,
+    gender                      varchar(10) NOT NULL,
+    language                    varchar(5) NOT NULL DEFAULT 'en',
+    created_at                  timestamp NOT NULL,
+    updated_at                  timestamp NOT NULL,
+    phone                       varchar(20),
+    preferred_channel           varchar(20) NOT NULL DEFAULT 'email',
+    otp_hash                    varchar(100),
+    otp_issued_at               timestamp,
+    otp_failed_attempts         int NOT NULL DEFAULT 0,
+    otp_locked_until            timestamp,
+    otp_channel                 varchar(20) NOT NULL DEFAULT '',
+    phone_verified_at           timestamp,
+    pending_phone               varchar(20),
+    pending_channel             varchar(20) NOT NULL DEFAULT '',
+    contact_otp_hash            varchar(100),
+    contact_otp_issued_at       timestamp,
+    contact_otp_failed_attempts int NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS doctors (
+    id            uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name          varchar(100) NOT NULL,
+    email         varchar(100) NOT NULL CONSTRAINT uni_doctors_email UNIQUE,
+    specialty     varchar(100) NOT NULL,
+    roomno        varchar(10) NOT NULL,
+    password_hash varchar(100)
+);
+
+CREATE TABLE IF NOT EXISTS admins (
+    id            uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name          varchar(100) NOT NULL,
+    email         varchar(100) NOT NULL CONSTRAINT uni_admins_email UNIQUE,
+    password_hash varchar(100) NOT NULL,
+    created_at    timestamp NOT NULL,
+    updated_at    timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id               uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id          uuid NOT NULL CONSTRAINT fk_users_sessions REFERENCES users (id),
+    weight           float NOT NULL,
+    height           float NOT NULL,
+    heartrate        float NOT NULL,
+    bodytemp         float NOT NULL,
+    prediagnosis     varchar(100),
+    doctor_diagnosis varchar(100),
+    created_at       timestamp NOT NULL,
+    updated_at       timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    role       varchar(50) NOT NULL,
+    content    text NOT NULL,
+    session_id uuid NOT NULL CONSTRAINT fk_sessions_messages REFERENCES sessions (id),
+    created_at timestamp DEFAULT now(),
+    updated_at timestamp DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS queues (
+    id                      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id               uuid NOT NULL CONSTRAINT fk_queues_doctor REFERENCES doctors (id),
+    session_id              uuid NOT NULL CONSTRAINT fk_queues_session REFERENCES sessions (id),
+    created_at              timestamp NOT NULL,
+    updated_at              timestamp NOT NULL,
+    service_date            date,
+    number                  int NOT NULL,
+    status                  varchar(20) NOT NULL DEFAULT 'waiting',
+    called_at               timestamp,
+    consultation_started_at timestamp,
+    completed_at            timestamp,
+    skipped_at              timestamp,
+    cancelled_at            timestamp,
+    reminder_sent_at        timestamp
+);
+
+CREATE TABLE IF NOT EXISTS queue_counters (
+    doctor_id    uuid NOT NULL,
+    service_date date NOT NULL,
+    last_number  int NOT NULL,
+    updated_at   timestamp NOT NULL,
+    PRIMARY KEY (doctor_id, service_date)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind            varchar(50) NOT NULL,
+    channel         varchar(20) NOT NULL DEFAULT 'email',
+    recipient       varchar(255) NOT NULL,
+    subject         varchar(255) NOT NULL,
+    text            text,
+    html            text,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    attempts        int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL,
+    last_error      text,
+    sent_at         timestamp,
+    expires_at      timestamp,
+    created_at      timestamp NOT NULL,
+    updated_at      timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_status_next_attempt ON outbox_messages (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject         uuid NOT NULL,
+    key             varchar(255) NOT NULL,
+    fingerprint     varchar(64) NOT NULL,
+    status          varchar(20) NOT NULL DEFAULT 'in_progress',
+    response_status int,
+    response_body   text,
+    created_at      timestamp NOT NULL,
+    updated_at      timestamp NOT NULL,
+    expires_at      timestamp NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_subject_key ON idempotency_keys (subject, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- columns added since the first release, missing from tables created by its AutoMigrate
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language varchar(5) NOT NULL DEFAULT 'en';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone varchar(20);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_channel varchar(20) NOT NULL DEFAULT 'email';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_hash varchar(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_issued_at timestamp;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_failed_attempts int NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_locked_until timestamp;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_channel varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at timestamp;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_phone varchar(20);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_channel varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS contact_otp_hash varchar(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS contact_otp_issued_at timestamp;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS contact_otp_failed_attempts int NOT NULL DEFAULT 0;
+
+-- the first release stored OTP codes in plain text
+ALTER TABLE users DROP COLUMN IF EXISTS otp;
+
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS password_hash varchar(100);
+
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS service_date date;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'waiting';
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS called_at timestamp;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS consultation_started_at timestamp;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS completed_at timestamp;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS skipped_at timestamp;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS cancelled_at timestamp;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS reminder_sent_at timestamp;
+
+-- backfill the service date of queue entries created before the column existed. created_at holds the server's
+-- UTC clock, the day is the one on the clinic's calendar, which is the session time zone set by the connection.
+UPDATE queues SET service_date = (created_at AT TIME ZONE 'UTC' AT TIME ZONE current_setting('TimeZone'))::date
+WHERE service_date IS NULL;
+
+-- entries that were diagnosed before the status column existed are done
+UPDATE queues SET status = 'done' FROM sessions
+WHERE sessions.id = queues.session_id AND sessions.doctor_diagnosis <> '' AND queues.status = 'waiting';
+
+-- a session has at most one ticket that is not cancelled, older duplicates from retried requests are cancelled
+UPDATE queues SET status = 'cancelled', cancelled_at = NOW() WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY session_id ORDER BY created_at DESC) AS position
+        FROM queues WHERE status <> 'cancelled'
+    ) ranked WHERE position > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queues_active_session ON queues (session_id) WHERE status <> 'cancelled';
+
+-- the first release numbered tickets by counting them, so concurrent bookings could share a number. Later
+-- copies get numbers after the last ticket of their day.
+WITH numbered AS (
+    SELECT id, doctor_id, service_date, created_at,
+           ROW_NUMBER() OVER (PARTITION BY doctor_id, service_date, number ORDER BY created_at, id) AS copy,
+           MAX(number) OVER (PARTITION BY doctor_id, service_date) AS last_number
+    FROM queues
+), duplicates AS (
+    SELECT id, last_number + ROW_NUMBER() OVER (PARTITION BY doctor_id, service_date ORDER BY created_at, id) AS number
+    FROM numbered WHERE copy > 1
+)
+UPDATE queues SET number = duplicates.number FROM duplicates WHERE queues.id = duplicates.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queues_doctor_date_number ON queues (doctor_id, service_date, number);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP INDEX IF EXISTS idx_messages_session_id_created_at;
+DROP INDEX IF EXISTS idx_queues_doctor_id_created_at;
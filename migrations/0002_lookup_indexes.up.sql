@@ -0,0 +1,4 @@
+-- a doctor's tickets by creation time, a session's chat history and a user's sessions
+CREATE INDEX IF NOT EXISTS idx_queues_doctor_id_created_at ON queues (doctor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_session_id_created_at ON messages (session_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
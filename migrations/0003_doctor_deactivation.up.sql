@@ -0,0 +1 @@
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS deactivated_at timestamp;
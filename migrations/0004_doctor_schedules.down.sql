@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS doctor_leaves;
+DROP TABLE IF EXISTS doctor_schedule_exceptions;
+DROP TABLE IF EXISTS doctor_shifts;
@@ -0,0 +1,36 @@
+-- weekly shifts, one-off working hours and leave of the doctors, times are HH:MM in the clinic's time zone
+CREATE TABLE IF NOT EXISTS doctor_shifts (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id  uuid NOT NULL CONSTRAINT fk_doctor_shifts_doctor REFERENCES doctors (id),
+    weekday    smallint NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time varchar(5) NOT NULL,
+    end_time   varchar(5) NOT NULL,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_doctor_shifts_doctor_id ON doctor_shifts (doctor_id);
+
+CREATE TABLE IF NOT EXISTS doctor_schedule_exceptions (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id  uuid NOT NULL CONSTRAINT fk_doctor_schedule_exceptions_doctor REFERENCES doctors (id),
+    date       date NOT NULL,
+    start_time varchar(5) NOT NULL,
+    end_time   varchar(5) NOT NULL,
+    reason     varchar(255),
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_doctor_schedule_exceptions_doctor_id_date ON doctor_schedule_exceptions (doctor_id, date);
+CREATE INDEX IF NOT EXISTS idx_doctor_schedule_exceptions_date ON doctor_schedule_exceptions (date);
+
+CREATE TABLE IF NOT EXISTS doctor_leaves (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doctor_id  uuid NOT NULL CONSTRAINT fk_doctor_leaves_doctor REFERENCES doctors (id),
+    start_date date NOT NULL,
+    end_date   date NOT NULL CHECK (end_date >= start_date),
+    reason     varchar(255),
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_doctor_leaves_doctor_id_end_date ON doctor_leaves (doctor_id, end_date);
+CREATE INDEX IF NOT EXISTS idx_doctor_leaves_end_date ON doctor_leaves (end_date);
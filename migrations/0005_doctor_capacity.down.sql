@@ -0,0 +1,5 @@
+ALTER TABLE queues DROP COLUMN IF EXISTS routing_reason;
+ALTER TABLE queues DROP COLUMN IF EXISTS requested_doctor_id;
+ALTER TABLE doctor_schedule_exceptions DROP COLUMN IF EXISTS capacity;
+ALTER TABLE doctor_shifts DROP COLUMN IF EXISTS capacity;
+ALTER TABLE doctors DROP COLUMN IF EXISTS daily_capacity;
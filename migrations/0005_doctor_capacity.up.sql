@@ -0,0 +1,8 @@
+-- daily and per-shift caps on the tickets a doctor takes, 0 is unlimited
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS daily_capacity int NOT NULL DEFAULT 0;
+ALTER TABLE doctor_shifts ADD COLUMN IF NOT EXISTS capacity int NOT NULL DEFAULT 0;
+ALTER TABLE doctor_schedule_exceptions ADD COLUMN IF NOT EXISTS capacity int NOT NULL DEFAULT 0;
+
+-- why a ticket went to another doctor or day than the one the LLM chose
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS requested_doctor_id uuid;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS routing_reason varchar(30) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- how the doctor of a ticket booked from the chat was picked
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS assignment_path varchar(30) NOT NULL DEFAULT '';
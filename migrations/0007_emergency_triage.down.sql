@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS emergency_alerted_at;
+ALTER TABLE queues DROP COLUMN IF EXISTS triage_reason;
+ALTER TABLE queues DROP COLUMN IF EXISTS priority;
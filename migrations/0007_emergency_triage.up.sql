@@ -0,0 +1,6 @@
+-- emergency tickets are served before the rest of the doctor's queue
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS priority boolean NOT NULL DEFAULT false;
+ALTER TABLE queues ADD COLUMN IF NOT EXISTS triage_reason varchar(255) NOT NULL DEFAULT '';
+
+-- staff are alerted about an emergency patient once per session, whether or not a ticket could be handed out
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS emergency_alerted_at timestamp;
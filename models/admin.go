@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Admin struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name         string    `json:"name" gorm:"type:varchar(100);not null"`
+	Email        string    `json:"email" gorm:"type:varchar(100);unique;not null"`
+	PasswordHash string    `json:"-" gorm:"type:varchar(100);not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
+}
@@ -1,9 +1,23 @@
 package models
 
+import "time"
+
 type Doctor struct {
-	ID        string `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Name      string `json:"name" gorm:"type:varchar(100);not null"`
-	Email     string `json:"email" gorm:"type:varchar(100);unique;not null"`
-	Specialty string `json:"specialty" gorm:"type:varchar(100);not null"`
-	Roomno 	 string `json:"roomno" gorm:"type:varchar(10);not null"`
+	ID           string `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name         string `json:"name" gorm:"type:varchar(100);not null"`
+	Email        string `json:"email" gorm:"type:varchar(100);unique;not null"`
+	Specialty    string `json:"specialty" gorm:"type:varchar(100);not null"`
+	Roomno       string `json:"roomno" gorm:"type:varchar(10);not null"`
+	PasswordHash string `json:"-" gorm:"type:varchar(100)"`
+
+	// most tickets the doctor takes per day, 0 falls back to DOCTOR_DAILY_CAPACITY
+	DailyCapacity int `json:"daily_capacity" gorm:"type:int;not null;default:0"`
+
+	// set when an admin deactivates the doctor, the row is kept so past queue entries still point to it
+	DeactivatedAt *time.Time `json:"deactivated_at" gorm:"type:timestamp"`
+}
+
+// IsActive reports whether the doctor can log in and take new patients.
+func (d *Doctor) IsActive() bool {
+	return d.DeactivatedAt == nil
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DoctorShift is a weekly working period of a doctor. Times are HH:MM in the clinic's time zone, the start
+// is inclusive and the end exclusive.
+type DoctorShift struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DoctorID  uuid.UUID `json:"doctor_id" gorm:"type:uuid;not null;index"`
+	Weekday   int       `json:"weekday" gorm:"type:smallint;not null"` // 0 is Sunday, as in time.Weekday
+	StartTime string    `json:"start_time" gorm:"type:varchar(5);not null"`
+	EndTime   string    `json:"end_time" gorm:"type:varchar(5);not null"`
+	Capacity  int       `json:"capacity" gorm:"type:int;not null;default:0"` // most tickets handed out during the shift, 0 is unlimited
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+}
+
+// DoctorScheduleException replaces the doctor's weekly shifts on one date with other working hours.
+// A date can have several exceptions, the doctor works during any of them.
+type DoctorScheduleException struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DoctorID  uuid.UUID `json:"doctor_id" gorm:"type:uuid;not null;index"`
+	Date      time.Time `json:"date" gorm:"type:date;not null"`
+	StartTime string    `json:"start_time" gorm:"type:varchar(5);not null"`
+	EndTime   string    `json:"end_time" gorm:"type:varchar(5);not null"`
+	Capacity  int       `json:"capacity" gorm:"type:int;not null;default:0"` // most tickets handed out during these hours, 0 is unlimited
+	Reason    string    `json:"reason" gorm:"type:varchar(255)"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+}
+
+// DoctorLeave is a range of days, both ends included, on which the doctor does not work at all.
+// Leave takes precedence over shifts and exceptions.
+type DoctorLeave struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DoctorID  uuid.UUID `json:"doctor_id" gorm:"type:uuid;not null;index"`
+	StartDate time.Time `json:"start_date" gorm:"type:date;not null"`
+	EndDate   time.Time `json:"end_date" gorm:"type:date;not null"`
+	Reason    string    `json:"reason" gorm:"type:varchar(255)"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Idempotency key statuses
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey remembers the response to a request sent with an Idempotency-Key header,
+// so a retried request gets the same response instead of being processed again.
+type IdempotencyKey struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Subject        uuid.UUID `json:"subject" gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_subject_key,priority:1"` // account that sent the request
+	Key            string    `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_subject_key,priority:2"`
+	Fingerprint    string    `json:"fingerprint" gorm:"type:varchar(64);not null"` // hash of the method, path and body
+	Status         string    `json:"status" gorm:"type:varchar(20);not null;default:'in_progress'"`
+	ResponseStatus int       `json:"response_status" gorm:"type:int"`
+	ResponseBody   string    `json:"response_body" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"type:timestamp;not null;index"`
+}
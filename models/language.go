@@ -0,0 +1,7 @@
+package models
+
+// Languages notifications can be sent in
+const (
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+)
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox message statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"    // gave up after the maximum number of attempts
+	OutboxStatusExpired = "expired" // not delivered before it expired
+)
+
+// OutboxMessage is a notification waiting to be delivered by the outbox worker.
+// It is written in the same transaction as the change that triggers it. The bodies are never returned by
+// the API, those of OTP messages are discarded once the worker is done with them.
+type OutboxMessage struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Kind          string     `json:"kind" gorm:"type:varchar(50);not null"`
+	Channel       string     `json:"channel" gorm:"type:varchar(20);not null;default:'email'"`
+	Recipient     string     `json:"recipient" gorm:"type:varchar(255);not null"`
+	Subject       string     `json:"subject" gorm:"type:varchar(255);not null"`
+	Text          string     `json:"-" gorm:"type:text"`
+	HTML          string     `json:"-" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_messages_status_next_attempt,priority:1"`
+	Attempts      int        `json:"attempts" gorm:"type:int;not null;default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"type:timestamp;not null;index:idx_outbox_messages_status_next_attempt,priority:2"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	SentAt        *time.Time `json:"sent_at" gorm:"type:timestamp"`
+	ExpiresAt     *time.Time `json:"expires_at" gorm:"type:timestamp"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"type:timestamp;not null"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"type:timestamp;not null"`
+}
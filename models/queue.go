@@ -6,13 +6,67 @@ import (
 	"github.com/google/uuid"
 )
 
+// Queue entry statuses
+const (
+	QueueStatusWaiting        = "waiting"
+	QueueStatusCalled         = "called"
+	QueueStatusInConsultation = "in_consultation"
+	QueueStatusDone           = "done"
+	QueueStatusSkipped        = "skipped"
+	QueueStatusCancelled      = "cancelled"
+)
+
+// Reasons a ticket went to another doctor or day than the one the LLM chose
+const (
+	QueueRoutingGeneralPractitioner = "general_practitioner" // the doctor was off shift, a general practitioner on duty took over
+	QueueRoutingSameSpecialty       = "same_specialty"       // the doctor was full, another doctor of the same specialty took over
+	QueueRoutingNextDay             = "next_available_day"   // booked on the doctor's next day with room
+	QueueRoutingDoctorFull          = "doctor_full"          // nobody could take the patient, no ticket was handed out
+	QueueRoutingNoDoctor            = "no_doctor_available"  // no doctor is on shift to take the patient, no ticket was handed out
+)
+
+// How the doctor of a ticket booked from the chat was picked
+const (
+	QueueAssignmentLLM                 = "llm"                  // the doctor the LLM chose
+	QueueAssignmentLLMCorrected        = "llm_corrected"        // the doctor the LLM chose when asked again
+	QueueAssignmentSpecialtyMatch      = "specialty_match"      // a doctor of the specialty the LLM named
+	QueueAssignmentGeneralPractitioner = "general_practitioner" // a general practitioner, nothing better was found
+	QueueAssignmentEmergency           = "emergency"            // the doctor on shift who could see an emergency soonest
+)
+
 type Queue struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	DoctorID  uuid.UUID `json:"doctor_id" gorm:"type:uuid;not null"`
-	Doctor    Doctor    `json:"doctor" gorm:"foreignKey:DoctorID"`
-	SessionID uuid.UUID `json:"session_id" gorm:"type:uuid;not null"`
-	Session   Session   `json:"session" gorm:"foreignKey:SessionID"`
-	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;not null"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
-	Number    int       `json:"number" gorm:"type:int;not null"`
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	DoctorID    uuid.UUID `json:"doctor_id" gorm:"type:uuid;not null;uniqueIndex:idx_queues_doctor_date_number,priority:1"`
+	Doctor      Doctor    `json:"doctor" gorm:"foreignKey:DoctorID"`
+	SessionID   uuid.UUID `json:"session_id" gorm:"type:uuid;not null"` // unique among entries that are not cancelled
+	Session     Session   `json:"session" gorm:"foreignKey:SessionID"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
+	ServiceDate time.Time `json:"service_date" gorm:"type:date;uniqueIndex:idx_queues_doctor_date_number,priority:2"`
+	Number      int       `json:"number" gorm:"type:int;not null;uniqueIndex:idx_queues_doctor_date_number,priority:3"`
+
+	// status lifecycle, each transition records when it happened
+	Status                string     `json:"status" gorm:"type:varchar(20);not null;default:'waiting'"`
+	CalledAt              *time.Time `json:"called_at" gorm:"type:timestamp"`
+	ConsultationStartedAt *time.Time `json:"consultation_started_at" gorm:"type:timestamp"`
+	CompletedAt           *time.Time `json:"completed_at" gorm:"type:timestamp"`
+	SkippedAt             *time.Time `json:"skipped_at" gorm:"type:timestamp"`
+	CancelledAt           *time.Time `json:"cancelled_at" gorm:"type:timestamp"`
+
+	// set when the ticket was routed away from the doctor the LLM chose, see the QueueRouting reasons
+	RequestedDoctorID *uuid.UUID `json:"requested_doctor_id" gorm:"type:uuid"`
+	RoutingReason     string     `json:"routing_reason" gorm:"type:varchar(30);not null;default:''"`
+
+	// how the doctor was picked, see the QueueAssignment paths, empty for tickets not booked from the chat
+	AssignmentPath string `json:"assignment_path" gorm:"type:varchar(30);not null;default:''"`
+
+	// emergency tickets are served before every other waiting ticket of the doctor
+	Priority     bool   `json:"priority" gorm:"not null;default:false"`
+	TriageReason string `json:"triage_reason" gorm:"type:varchar(255);not null;default:''"` // the red flag that made the ticket a priority
+
+	// set when the "your turn is near" reminder has been queued, so it is only sent once
+	ReminderSentAt *time.Time `json:"reminder_sent_at" gorm:"type:timestamp"`
+
+	// computed when the entry is read, nil once the ticket is no longer waiting to be seen
+	EstimatedWaitMinutes *int `json:"estimated_wait_minutes,omitempty" gorm:"-"`
 }
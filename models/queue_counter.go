@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueueCounter holds the last queue number handed out for a doctor on a service date.
+// Its row is locked while a new number is allocated so concurrent allocations are serialized.
+type QueueCounter struct {
+	DoctorID    uuid.UUID `json:"doctor_id" gorm:"type:uuid;primaryKey"`
+	ServiceDate time.Time `json:"service_date" gorm:"type:date;primaryKey"`
+	LastNumber  int       `json:"last_number" gorm:"type:int;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Roles carried in the access token and used for route authorization
+const (
+	RolePatient = "patient"
+	RoleDoctor  = "doctor"
+	RoleAdmin   = "admin"
+)
@@ -17,6 +17,8 @@ type Session struct {
 	Messages        []Message `json:"messages" gorm:"foreignKey:SessionID"`
 	Prediagnosis    string    `json:"prediagnosis" gorm:"type:varchar(100);"`
 	DoctorDiagnosis string    `json:"doctor_diagnosis" gorm:"type:varchar(100);"`
-	CreatedAt       time.Time `json:"created_at" gorm:"type:timestamp;not null"`
-	UpdatedAt       time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
+	// set when staff were alerted about the patient as an emergency, they are alerted once per session
+	EmergencyAlertedAt *time.Time `json:"emergency_alerted_at" gorm:"type:timestamp"`
+	CreatedAt          time.Time  `json:"created_at" gorm:"type:timestamp;not null"`
+	UpdatedAt          time.Time  `json:"updated_at" gorm:"type:timestamp;not null"`
 }
@@ -13,8 +13,28 @@ type User struct {
 	Nationality string    `json:"nationality" gorm:"type:varchar(100);not null"`
 	DOB         string    `json:"dob" gorm:"type:date;not null"`
 	Gender      string    `json:"gender" gorm:"type:varchar(10);not null"`
-	OTP         string    `json:"-" gorm:"type:varchar(6)"`
+	Language    string    `json:"language" gorm:"type:varchar(5);not null;default:'en'"`
 	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;not null"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;not null"`
 	Sessions    []Session `json:"sessions" gorm:"foreignKey:UserID"`
+
+	// optional phone number and the channel used for OTP codes, queue tickets and reminders. Messages only go to
+	// the phone once the user proved they own it by entering a code sent to it.
+	Phone            *string    `json:"phone" gorm:"type:varchar(20)"`
+	PhoneVerifiedAt  *time.Time `json:"phone_verified_at" gorm:"type:timestamp"`
+	PreferredChannel string     `json:"preferred_channel" gorm:"type:varchar(20);not null;default:'email'"`
+
+	// OTP state, the code itself is only stored as a bcrypt hash
+	OTPHash           string     `json:"-" gorm:"type:varchar(100)"`
+	OTPIssuedAt       *time.Time `json:"-" gorm:"type:timestamp"`
+	OTPFailedAttempts int        `json:"-" gorm:"type:int;not null;default:0"`
+	OTPLockedUntil    *time.Time `json:"-" gorm:"type:timestamp"`
+	OTPChannel        string     `json:"-" gorm:"type:varchar(20);not null;default:''"` // the channel the current code was sent on
+
+	// a phone number change waiting for the code sent to the new number
+	PendingPhone             *string    `json:"-" gorm:"type:varchar(20)"`
+	PendingChannel           string     `json:"-" gorm:"type:varchar(20);not null;default:''"`
+	ContactOTPHash           string     `json:"-" gorm:"type:varchar(100)"`
+	ContactOTPIssuedAt       *time.Time `json:"-" gorm:"type:timestamp"`
+	ContactOTPFailedAttempts int        `json:"-" gorm:"type:int;not null;default:0"`
 }
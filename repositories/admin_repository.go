@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type AdminRepository interface {
+	FindByID(id uuid.UUID) (*models.Admin, error)
+	FindByEmail(email string) (*models.Admin, error)
+	Create(admin *models.Admin) error
+}
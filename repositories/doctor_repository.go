@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type DoctorRepository interface {
+	// FindAll returns every doctor ordered by name, including deactivated ones.
+	FindAll() ([]models.Doctor, error)
+	// FindActive returns the doctors that have not been deactivated ordered by name.
+	FindActive() ([]models.Doctor, error)
+	FindByID(id uuid.UUID) (*models.Doctor, error)
+	FindByEmail(email string) (*models.Doctor, error)
+	// Create stores a new doctor, it returns ErrDuplicate if the email is taken.
+	Create(doctor *models.Doctor) error
+	Save(doctor *models.Doctor) error
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormAdminRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAdminRepository) FindByID(id uuid.UUID) (*models.Admin, error) {
+	var admin models.Admin
+	if err := r.db.First(&admin, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &admin, nil
+}
+
+func (r *gormAdminRepository) FindByEmail(email string) (*models.Admin, error) {
+	var admin models.Admin
+	if err := r.db.Where("email = ?", email).First(&admin).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &admin, nil
+}
+
+func (r *gormAdminRepository) Create(admin *models.Admin) error {
+	return translateError(r.db.Create(admin).Error)
+}
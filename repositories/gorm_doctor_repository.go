@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormDoctorRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormDoctorRepository) FindAll() ([]models.Doctor, error) {
+	var doctors []models.Doctor
+	err := r.db.Order("name ASC").Find(&doctors).Error
+	return doctors, translateError(err)
+}
+
+func (r *gormDoctorRepository) FindActive() ([]models.Doctor, error) {
+	var doctors []models.Doctor
+	err := r.db.Where("deactivated_at IS NULL").Order("name ASC").Find(&doctors).Error
+	return doctors, translateError(err)
+}
+
+func (r *gormDoctorRepository) FindByID(id uuid.UUID) (*models.Doctor, error) {
+	var doctor models.Doctor
+	if err := r.db.First(&doctor, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &doctor, nil
+}
+
+func (r *gormDoctorRepository) FindByEmail(email string) (*models.Doctor, error) {
+	var doctor models.Doctor
+	if err := r.db.Where("email = ?", email).First(&doctor).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &doctor, nil
+}
+
+func (r *gormDoctorRepository) Create(doctor *models.Doctor) error {
+	return translateError(r.db.Create(doctor).Error)
+}
+
+func (r *gormDoctorRepository) Save(doctor *models.Doctor) error {
+	return translateError(r.db.Save(doctor).Error)
+}
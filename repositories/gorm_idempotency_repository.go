@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormIdempotencyRepository) Create(key *models.IdempotencyKey) error {
+	return translateError(r.db.Create(key).Error)
+}
+
+func (r *gormIdempotencyRepository) Save(key *models.IdempotencyKey) error {
+	return translateError(r.db.Save(key).Error)
+}
+
+func (r *gormIdempotencyRepository) Delete(id uuid.UUID) error {
+	return translateError(r.db.Delete(&models.IdempotencyKey{}, "id = ?", id).Error)
+}
+
+func (r *gormIdempotencyRepository) Find(subject uuid.UUID, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.Where("subject = ? AND key = ?", subject, key).First(&record).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+func (r *gormIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormMessageRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormMessageRepository) Create(message *models.Message) error {
+	return translateError(r.db.Omit("Session").Create(message).Error)
+}
+
+func (r *gormMessageRepository) FindBySessionID(sessionID uuid.UUID) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("session_id = ?", sessionID).Order("created_at ASC").Find(&messages).Error
+	return messages, translateError(err)
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxRepository) Create(message *models.OutboxMessage) error {
+	return translateError(r.db.Create(message).Error)
+}
+
+func (r *gormOutboxRepository) Save(message *models.OutboxMessage) error {
+	return translateError(r.db.Save(message).Error)
+}
+
+func (r *gormOutboxRepository) FindByID(id uuid.UUID) (*models.OutboxMessage, error) {
+	var message models.OutboxMessage
+	if err := r.db.First(&message, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &message, nil
+}
+
+func (r *gormOutboxRepository) LockDue(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, translateError(err)
+}
+
+func (r *gormOutboxRepository) List(status string, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&messages).Error
+	return messages, translateError(err)
+}
+
+func (r *gormOutboxRepository) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&models.OutboxMessage{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormQueueRepository struct {
+	db *gorm.DB
+}
+
+// AllocateNumber increments the doctor's counter row for the service date, the row stays locked until the
+// transaction commits so concurrent allocations are serialized. The first allocation of a day seeds the
+// counter from existing entries so numbers never collide with them.
+func (r *gormQueueRepository) AllocateNumber(doctorID uuid.UUID, serviceDate time.Time) (int, error) {
+	var number int
+	err := r.db.Raw(`
+		INSERT INTO queue_counters (doctor_id, service_date, last_number, updated_at)
+		VALUES (
+			@doctor_id, @service_date,
+			(SELECT COALESCE(MAX(number), 0) + 1 FROM queues WHERE doctor_id = @doctor_id AND service_date = @service_date),
+			NOW()
+		)
+		ON CONFLICT (doctor_id, service_date)
+		DO UPDATE SET last_number = queue_counters.last_number + 1, updated_at = NOW()
+		RETURNING last_number`,
+		map[string]interface{}{"doctor_id": doctorID, "service_date": serviceDate},
+	).Scan(&number).Error
+	return number, translateError(err)
+}
+
+func (r *gormQueueRepository) Create(queue *models.Queue) error {
+	return translateError(r.db.Omit("Doctor", "Session").Create(queue).Error)
+}
+
+func (r *gormQueueRepository) Save(queue *models.Queue) error {
+	return translateError(r.db.Omit("Doctor", "Session").Save(queue).Error)
+}
+
+func (r *gormQueueRepository) FindByID(id uuid.UUID) (*models.Queue, error) {
+	var queue models.Queue
+	if err := r.db.First(&queue, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) FindWithDetails(id uuid.UUID) (*models.Queue, error) {
+	var queue models.Queue
+	if err := r.db.Preload("Doctor").Preload("Session.User").First(&queue, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) FindLatestBySessionID(sessionID uuid.UUID) (*models.Queue, error) {
+	var queue models.Queue
+	if err := r.db.Where("session_id = ?", sessionID).Order("created_at DESC").First(&queue).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) FindActiveBySessionID(sessionID uuid.UUID) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.db.Where("session_id = ? AND status <> ?", sessionID, models.QueueStatusCancelled).First(&queue).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) FindServing(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.db.
+		Where("doctor_id = ? AND service_date = ?", doctorID, serviceDate).
+		Where("status IN ?", []string{models.QueueStatusCalled, models.QueueStatusInConsultation}).
+		Order("called_at DESC").
+		Preload("Session").
+		First(&queue).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) FindNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.db.
+		Where("doctor_id = ? AND service_date = ? AND status = ?", doctorID, serviceDate, models.QueueStatusWaiting).
+		Order("priority DESC, number ASC").
+		Preload("Session").
+		First(&queue).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+// applyFilter narrows the query down to the entries matching the filter
+func (r *gormQueueRepository) applyFilter(filter QueueFilter) *gorm.DB {
+	query := r.db.Model(&models.Queue{})
+	if filter.DoctorID != uuid.Nil {
+		query = query.Where("doctor_id = ?", filter.DoctorID)
+	}
+	if filter.ServiceDate != nil {
+		query = query.Where("service_date = ?", *filter.ServiceDate)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.BeforeNumber > 0 {
+		query = query.Where("number < ?", filter.BeforeNumber)
+	}
+	if filter.Priority != nil {
+		query = query.Where("priority = ?", *filter.Priority)
+	}
+	if filter.NotReminded {
+		query = query.Where("reminder_sent_at IS NULL")
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", asTimestamp(*filter.CreatedFrom))
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", asTimestamp(*filter.CreatedBefore))
+	}
+	return query
+}
+
+func (r *gormQueueRepository) Find(filter QueueFilter) ([]models.Queue, error) {
+	var queues []models.Queue
+	err := r.applyFilter(filter).Order("priority DESC, number ASC").Find(&queues).Error
+	return queues, translateError(err)
+}
+
+func (r *gormQueueRepository) Count(filter QueueFilter) (int, error) {
+	var count int64
+	err := r.applyFilter(filter).Count(&count).Error
+	return int(count), translateError(err)
+}
+
+func (r *gormQueueRepository) LockNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("doctor_id = ? AND service_date = ? AND status = ?", doctorID, serviceDate, models.QueueStatusWaiting).
+		Order("priority DESC, number ASC").
+		First(&queue).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) LockByID(id uuid.UUID, doctorID uuid.UUID) (*models.Queue, error) {
+	var queue models.Queue
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND doctor_id = ?", id, doctorID).
+		First(&queue).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &queue, nil
+}
+
+func (r *gormQueueRepository) AverageConsultationDuration(doctorID uuid.UUID, sampleSize int) (time.Duration, error) {
+	var seconds *float64
+	err := r.db.Raw(`
+		SELECT AVG(EXTRACT(EPOCH FROM (completed_at - called_at)))
+		FROM (
+			SELECT called_at, completed_at FROM queues
+			WHERE doctor_id = ? AND status = ? AND called_at IS NOT NULL AND completed_at > called_at
+			ORDER BY completed_at DESC
+			LIMIT ?
+		) recent`,
+		doctorID, models.QueueStatusDone, sampleSize,
+	).Scan(&seconds).Error
+	if err != nil || seconds == nil {
+		return 0, translateError(err)
+	}
+
+	return time.Duration(*seconds * float64(time.Second)), nil
+}
+
+func (r *gormQueueRepository) DoctorIDsWithPendingReminders(serviceDate time.Time) ([]uuid.UUID, error) {
+	var doctorIDs []uuid.UUID
+	err := r.db.Model(&models.Queue{}).
+		Where("service_date = ? AND status = ? AND reminder_sent_at IS NULL", serviceDate, models.QueueStatusWaiting).
+		Distinct().
+		Pluck("doctor_id", &doctorIDs).Error
+	return doctorIDs, translateError(err)
+}
+
+func (r *gormQueueRepository) ClaimReminder(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&models.Queue{}).
+		Where("id = ? AND status = ? AND reminder_sent_at IS NULL", id, models.QueueStatusWaiting).
+		Update("reminder_sent_at", at)
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
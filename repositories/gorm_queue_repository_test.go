@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+func TestGormAllocateNumberInParallelTransactions(t *testing.T) {
+	const bookings = 20
+
+	store := newTestGormStore(t)
+	doctorID := createTestDoctor(t, store)
+	serviceDate := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	sessionIDs := make([]uuid.UUID, bookings)
+	for i := range sessionIDs {
+		sessionIDs[i] = createTestSession(t, store)
+	}
+
+	numbers := make([]int, bookings)
+	errs := make([]error, bookings)
+	var wg sync.WaitGroup
+	for i, sessionID := range sessionIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.Transaction(func(tx Store) error {
+				number, err := tx.Queues().AllocateNumber(doctorID, serviceDate)
+				if err != nil {
+					return err
+				}
+				now := time.Now()
+				numbers[i] = number
+				// the unique index on doctor, day and number rejects a duplicate that slipped through
+				return tx.Queues().Create(&models.Queue{
+					ID:          uuid.New(),
+					DoctorID:    doctorID,
+					SessionID:   sessionID,
+					CreatedAt:   now,
+					UpdatedAt:   now,
+					ServiceDate: serviceDate,
+					Number:      number,
+					Status:      models.QueueStatusWaiting,
+				})
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("booking %d failed: %v", i, err)
+		}
+	}
+
+	slices.Sort(numbers)
+	for i, number := range numbers {
+		if number != i+1 {
+			t.Fatalf("expected numbers 1 to %d without gaps or duplicates, got %v", bookings, numbers)
+		}
+	}
+}
+
+func TestGormCountCreatedWindowInClinicZone(t *testing.T) {
+	// the server runs in UTC while the clinic is seven hours ahead, as in a container serving a clinic in Jakarta
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	clinic := time.FixedZone("WIB", 7*60*60)
+	store := newTestGormStore(t)
+	doctorID := createTestDoctor(t, store)
+	serviceDate := time.Date(2025, 3, 3, 0, 0, 0, 0, clinic)
+
+	// tickets are stamped with time.Now(), which is in the server's zone
+	createdAt := []time.Time{
+		time.Date(2025, 3, 3, 7, 30, 0, 0, clinic).Local(),  // before the shift
+		time.Date(2025, 3, 3, 9, 0, 0, 0, clinic).Local(),   // during the shift
+		time.Date(2025, 3, 3, 11, 59, 0, 0, clinic).Local(), // during the shift
+		time.Date(2025, 3, 3, 12, 0, 0, 0, clinic).Local(),  // after the shift
+	}
+	for _, at := range createdAt {
+		number, err := store.Queues().AllocateNumber(doctorID, serviceDate)
+		if err != nil {
+			t.Fatalf("AllocateNumber failed: %v", err)
+		}
+		queue := models.Queue{
+			ID:          uuid.New(),
+			DoctorID:    doctorID,
+			SessionID:   createTestSession(t, store),
+			CreatedAt:   at,
+			UpdatedAt:   at,
+			ServiceDate: serviceDate,
+			Number:      number,
+			Status:      models.QueueStatusWaiting,
+		}
+		if err := store.Queues().Create(&queue); err != nil {
+			t.Fatalf("failed to create ticket: %v", err)
+		}
+	}
+
+	// an 08:00 to 12:00 shift on the clinic's clock
+	from := time.Date(2025, 3, 3, 8, 0, 0, 0, clinic)
+	before := time.Date(2025, 3, 3, 12, 0, 0, 0, clinic)
+	count, err := store.Queues().Count(QueueFilter{
+		DoctorID:      doctorID,
+		ServiceDate:   &serviceDate,
+		CreatedFrom:   &from,
+		CreatedBefore: &before,
+	})
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected the two tickets booked during the shift, got %d", count)
+	}
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormScheduleRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormScheduleRepository) FindShifts(doctorID uuid.UUID) ([]models.DoctorShift, error) {
+	query := r.db.Order("weekday ASC, start_time ASC")
+	if doctorID != uuid.Nil {
+		query = query.Where("doctor_id = ?", doctorID)
+	}
+
+	var shifts []models.DoctorShift
+	err := query.Find(&shifts).Error
+	return shifts, translateError(err)
+}
+
+func (r *gormScheduleRepository) ReplaceShifts(doctorID uuid.UUID, shifts []models.DoctorShift) error {
+	if err := r.db.Where("doctor_id = ?", doctorID).Delete(&models.DoctorShift{}).Error; err != nil {
+		return translateError(err)
+	}
+	if len(shifts) == 0 {
+		return nil
+	}
+	return translateError(r.db.Create(&shifts).Error)
+}
+
+// applyFilter narrows the query down to the entries matching the filter, the columns holding the first
+// and last day of an entry differ between exceptions and leave
+func (r *gormScheduleRepository) applyFilter(model any, startColumn string, endColumn string, filter ScheduleFilter) *gorm.DB {
+	query := r.db.Model(model)
+	if filter.DoctorID != uuid.Nil {
+		query = query.Where("doctor_id = ?", filter.DoctorID)
+	}
+	if filter.From != nil {
+		query = query.Where(endColumn+" >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where(startColumn+" <= ?", *filter.To)
+	}
+	return query
+}
+
+func (r *gormScheduleRepository) FindExceptions(filter ScheduleFilter) ([]models.DoctorScheduleException, error) {
+	var exceptions []models.DoctorScheduleException
+	err := r.applyFilter(&models.DoctorScheduleException{}, "date", "date", filter).
+		Order("date ASC, start_time ASC").
+		Find(&exceptions).Error
+	return exceptions, translateError(err)
+}
+
+func (r *gormScheduleRepository) CreateException(exception *models.DoctorScheduleException) error {
+	return translateError(r.db.Create(exception).Error)
+}
+
+func (r *gormScheduleRepository) DeleteException(doctorID uuid.UUID, id uuid.UUID) error {
+	result := r.db.Where("id = ? AND doctor_id = ?", id, doctorID).Delete(&models.DoctorScheduleException{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormScheduleRepository) FindLeaves(filter ScheduleFilter) ([]models.DoctorLeave, error) {
+	var leaves []models.DoctorLeave
+	err := r.applyFilter(&models.DoctorLeave{}, "start_date", "end_date", filter).
+		Order("start_date ASC").
+		Find(&leaves).Error
+	return leaves, translateError(err)
+}
+
+func (r *gormScheduleRepository) CreateLeave(leave *models.DoctorLeave) error {
+	return translateError(r.db.Create(leave).Error)
+}
+
+func (r *gormScheduleRepository) DeleteLeave(doctorID uuid.UUID, id uuid.UUID) error {
+	result := r.db.Where("id = ? AND doctor_id = ?", id, doctorID).Delete(&models.DoctorLeave{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
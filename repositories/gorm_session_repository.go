@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) FindByID(id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.First(&session, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) FindWithMessages(id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	err := r.db.
+		Preload("User").
+		Preload("Messages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		First(&session, "id = ?", id).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) FindByUserID(userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error
+	return sessions, translateError(err)
+}
+
+func (r *gormSessionRepository) Create(session *models.Session) error {
+	return translateError(r.db.Create(session).Error)
+}
+
+func (r *gormSessionRepository) Save(session *models.Session) error {
+	return translateError(r.db.Omit("User", "Messages").Save(session).Error)
+}
+
+func (r *gormSessionRepository) MarkEmergencyAlerted(id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND emergency_alerted_at IS NULL", id).
+		Update("emergency_alerted_at", at)
+	return result.RowsAffected > 0, translateError(result.Error)
+}
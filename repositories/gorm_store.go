@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormStore stores the models in Postgres through GORM.
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Users() UserRepository       { return &gormUserRepository{db: s.db} }
+func (s *GormStore) Sessions() SessionRepository { return &gormSessionRepository{db: s.db} }
+func (s *GormStore) Messages() MessageRepository { return &gormMessageRepository{db: s.db} }
+func (s *GormStore) Doctors() DoctorRepository   { return &gormDoctorRepository{db: s.db} }
+func (s *GormStore) Admins() AdminRepository     { return &gormAdminRepository{db: s.db} }
+func (s *GormStore) Queues() QueueRepository     { return &gormQueueRepository{db: s.db} }
+func (s *GormStore) Outbox() OutboxRepository    { return &gormOutboxRepository{db: s.db} }
+func (s *GormStore) IdempotencyKeys() IdempotencyRepository {
+	return &gormIdempotencyRepository{db: s.db}
+}
+
+func (s *GormStore) Schedules() ScheduleRepository {
+	return &gormScheduleRepository{db: s.db}
+}
+
+func (s *GormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormStore{db: tx})
+	})
+}
+
+// asTimestamp converts a bound compared with a timestamp column to the server's local time. The columns hold the
+// wall clock of time.Now() without its zone and the driver drops the zone of parameters too, so a bound in
+// another zone, such as the clinic's, would be off by the difference between the zones.
+func asTimestamp(t time.Time) time.Time {
+	return t.In(time.Local)
+}
+
+// translateError maps GORM errors to the repository errors
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	default:
+		return err
+	}
+}
+
+var _ Store = (*GormStore)(nil)
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/migrations"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestGormStore connects to the Postgres database in TEST_DATABASE_URL and migrates it, the test is skipped
+// without one. Tests create their own doctors and patients, so they can share the database.
+func newTestGormStore(t *testing.T) *GormStore {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return NewGormStore(db)
+}
+
+// createTestDoctor stores a doctor for the queue entries to point to
+func createTestDoctor(t *testing.T, store Store) uuid.UUID {
+	t.Helper()
+
+	doctor := models.Doctor{
+		ID:        uuid.NewString(),
+		Name:      "Dr. General",
+		Email:     uuid.NewString() + "@omsehat.local",
+		Specialty: "General Practitioner",
+		Roomno:    "101",
+	}
+	if err := store.Doctors().Create(&doctor); err != nil {
+		t.Fatalf("failed to create doctor: %v", err)
+	}
+	return uuid.MustParse(doctor.ID)
+}
+
+// createTestSession stores a patient and a session of theirs
+func createTestSession(t *testing.T, store Store) uuid.UUID {
+	t.Helper()
+
+	now := time.Now()
+	user := models.User{
+		ID:          uuid.New(),
+		Name:        "Patient",
+		Email:       uuid.NewString() + "@omsehat.local",
+		Nationality: "Indonesia",
+		DOB:         "1990-01-01",
+		Gender:      "female",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	session := models.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Heartrate: 80,
+		Bodytemp:  36.8,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.Sessions().Create(&session); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return session.ID
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) FindByID(id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return translateError(r.db.Create(user).Error)
+}
+
+func (r *gormUserRepository) Save(user *models.User) error {
+	return translateError(r.db.Save(user).Error)
+}
+
+func (r *gormUserRepository) IncrementFailedOTPAttempts(id uuid.UUID) (int, error) {
+	var attempts int
+	err := r.db.
+		Raw("UPDATE users SET otp_failed_attempts = otp_failed_attempts + 1 WHERE id = ? RETURNING otp_failed_attempts", id).
+		Scan(&attempts).Error
+	return attempts, translateError(err)
+}
+
+func (r *gormUserRepository) ResetOTP(id uuid.UUID, lockedUntil *time.Time) error {
+	err := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"otp_hash":            "",
+		"otp_issued_at":       nil,
+		"otp_failed_attempts": 0,
+		"otp_locked_until":    lockedUntil,
+	}).Error
+	return translateError(err)
+}
+
+func (r *gormUserRepository) IncrementFailedContactOTPAttempts(id uuid.UUID) (int, error) {
+	var attempts int
+	err := r.db.
+		Raw("UPDATE users SET contact_otp_failed_attempts = contact_otp_failed_attempts + 1 WHERE id = ? RETURNING contact_otp_failed_attempts", id).
+		Scan(&attempts).Error
+	return attempts, translateError(err)
+}
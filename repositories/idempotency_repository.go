@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type IdempotencyRepository interface {
+	// Create stores a new key, it returns ErrDuplicate if the account already used the key.
+	Create(key *models.IdempotencyKey) error
+	Save(key *models.IdempotencyKey) error
+	Delete(id uuid.UUID) error
+	Find(subject uuid.UUID, key string) (*models.IdempotencyKey, error)
+	// DeleteExpired removes the keys that expired before now and returns how many were removed.
+	DeleteExpired(now time.Time) (int64, error)
+}
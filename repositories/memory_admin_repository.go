@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryAdminRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAdminRepository) FindByID(id uuid.UUID) (*models.Admin, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	admin, ok := r.store.data.admins[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &admin, nil
+}
+
+func (r *memoryAdminRepository) FindByEmail(email string) (*models.Admin, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, admin := range r.store.data.admins {
+		if admin.Email == email {
+			return &admin, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAdminRepository) Create(admin *models.Admin) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, other := range r.store.data.admins {
+		if other.Email == admin.Email {
+			return fmt.Errorf("%w: email %s", ErrDuplicate, admin.Email)
+		}
+	}
+
+	if admin.ID == uuid.Nil {
+		admin.ID = uuid.New()
+	}
+	r.store.data.admins[admin.ID] = *admin
+	return nil
+}
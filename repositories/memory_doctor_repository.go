@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryDoctorRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryDoctorRepository) FindAll() ([]models.Doctor, error) {
+	return r.find(false)
+}
+
+func (r *memoryDoctorRepository) FindActive() ([]models.Doctor, error) {
+	return r.find(true)
+}
+
+// find returns the doctors ordered by name, optionally only the active ones
+func (r *memoryDoctorRepository) find(activeOnly bool) ([]models.Doctor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doctors := make([]models.Doctor, 0, len(r.store.data.doctors))
+	for _, doctor := range r.store.data.doctors {
+		if activeOnly && !doctor.IsActive() {
+			continue
+		}
+		doctors = append(doctors, doctor)
+	}
+	slices.SortFunc(doctors, func(a, b models.Doctor) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return doctors, nil
+}
+
+func (r *memoryDoctorRepository) FindByID(id uuid.UUID) (*models.Doctor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	doctor, ok := r.store.data.doctors[id.String()]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &doctor, nil
+}
+
+func (r *memoryDoctorRepository) FindByEmail(email string) (*models.Doctor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, doctor := range r.store.data.doctors {
+		if doctor.Email == email {
+			return &doctor, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryDoctorRepository) Create(doctor *models.Doctor) error {
+	return r.Save(doctor)
+}
+
+func (r *memoryDoctorRepository) Save(doctor *models.Doctor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if doctor.ID == "" {
+		doctor.ID = uuid.NewString()
+	}
+	for id, other := range r.store.data.doctors {
+		if id != doctor.ID && other.Email == doctor.Email {
+			return fmt.Errorf("%w: email %s", ErrDuplicate, doctor.Email)
+		}
+	}
+
+	r.store.data.doctors[doctor.ID] = *doctor
+	return nil
+}
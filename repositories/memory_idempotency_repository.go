@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryIdempotencyRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryIdempotencyRepository) Create(key *models.IdempotencyKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, other := range r.store.data.idempotencyKeys {
+		if other.Subject == key.Subject && other.Key == key.Key {
+			return fmt.Errorf("%w: idempotency key %s", ErrDuplicate, key.Key)
+		}
+	}
+
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	r.store.data.idempotencyKeys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Save(key *models.IdempotencyKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	r.store.data.idempotencyKeys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Delete(id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.data.idempotencyKeys, id)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Find(subject uuid.UUID, key string) (*models.IdempotencyKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, record := range r.store.data.idempotencyKeys {
+		if record.Subject == subject && record.Key == key {
+			return &record, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	for id, record := range r.store.data.idempotencyKeys {
+		if record.ExpiresAt.Before(now) {
+			delete(r.store.data.idempotencyKeys, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"slices"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryMessageRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryMessageRepository) Create(message *models.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+
+	stored := *message
+	stored.Session = models.Session{}
+	r.store.data.messages[message.ID] = stored
+	return nil
+}
+
+func (r *memoryMessageRepository) FindBySessionID(sessionID uuid.UUID) ([]models.Message, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return sessionMessages(r.store.data, sessionID), nil
+}
+
+// sessionMessages returns the session's messages in chronological order, the caller holds the lock
+func sessionMessages(data *memoryData, sessionID uuid.UUID) []models.Message {
+	var messages []models.Message
+	for _, message := range data.messages {
+		if message.SessionID == sessionID {
+			messages = append(messages, message)
+		}
+	}
+	slices.SortFunc(messages, func(a, b models.Message) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return messages
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryOutboxRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryOutboxRepository) Create(message *models.OutboxMessage) error {
+	return r.Save(message)
+}
+
+func (r *memoryOutboxRepository) Save(message *models.OutboxMessage) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+	r.store.data.outbox[message.ID] = *message
+	return nil
+}
+
+func (r *memoryOutboxRepository) FindByID(id uuid.UUID) (*models.OutboxMessage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	message, ok := r.store.data.outbox[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &message, nil
+}
+
+// LockDue does not need a lock in memory, transactions are serialized
+func (r *memoryOutboxRepository) LockDue(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var messages []models.OutboxMessage
+	for _, message := range r.store.data.outbox {
+		if message.Status == models.OutboxStatusPending && !message.NextAttemptAt.After(now) {
+			messages = append(messages, message)
+		}
+	}
+	slices.SortFunc(messages, func(a, b models.OutboxMessage) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *memoryOutboxRepository) List(status string, limit int) ([]models.OutboxMessage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var messages []models.OutboxMessage
+	for _, message := range r.store.data.outbox {
+		if status == "" || message.Status == status {
+			messages = append(messages, message)
+		}
+	}
+	slices.SortFunc(messages, func(a, b models.OutboxMessage) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *memoryOutboxRepository) CountByStatus() (map[string]int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	counts := map[string]int64{}
+	for _, message := range r.store.data.outbox {
+		counts[message.Status]++
+	}
+	return counts, nil
+}
@@ -0,0 +1,332 @@
+package repositories
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryQueueRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryQueueRepository) AllocateNumber(doctorID uuid.UUID, serviceDate time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := queueCounterKey{doctorID: doctorID, serviceDate: serviceDate.Format(time.DateOnly)}
+	last, ok := r.store.data.counters[key]
+	if !ok {
+		// seed the counter from existing entries, like the first allocation of a day in Postgres
+		for _, queue := range r.store.data.queues {
+			if queue.DoctorID == doctorID && sameDate(queue.ServiceDate, serviceDate) && queue.Number > last {
+				last = queue.Number
+			}
+		}
+	}
+
+	r.store.data.counters[key] = last + 1
+	return last + 1, nil
+}
+
+func (r *memoryQueueRepository) Create(queue *models.Queue) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, other := range r.store.data.queues {
+		if other.DoctorID == queue.DoctorID && sameDate(other.ServiceDate, queue.ServiceDate) && other.Number == queue.Number {
+			return fmt.Errorf("%w: queue number %d", ErrDuplicate, queue.Number)
+		}
+	}
+	if err := r.checkActiveSession(queue); err != nil {
+		return err
+	}
+
+	if queue.ID == uuid.Nil {
+		queue.ID = uuid.New()
+	}
+	r.put(queue)
+	return nil
+}
+
+func (r *memoryQueueRepository) Save(queue *models.Queue) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkActiveSession(queue); err != nil {
+		return err
+	}
+
+	if queue.ID == uuid.Nil {
+		queue.ID = uuid.New()
+	}
+	r.put(queue)
+	return nil
+}
+
+// checkActiveSession enforces one entry per session that has not been cancelled, like the partial unique
+// index in Postgres, the caller holds the lock
+func (r *memoryQueueRepository) checkActiveSession(queue *models.Queue) error {
+	if queue.Status == models.QueueStatusCancelled {
+		return nil
+	}
+	for _, other := range r.store.data.queues {
+		if other.ID != queue.ID && other.SessionID == queue.SessionID && other.Status != models.QueueStatusCancelled {
+			return fmt.Errorf("%w: session %s already has an active queue entry", ErrDuplicate, queue.SessionID)
+		}
+	}
+	return nil
+}
+
+// put stores the entry without its associations, the caller holds the lock
+func (r *memoryQueueRepository) put(queue *models.Queue) {
+	stored := *queue
+	stored.Doctor = models.Doctor{}
+	stored.Session = models.Session{}
+	stored.EstimatedWaitMinutes = nil
+	r.store.data.queues[queue.ID] = stored
+}
+
+func (r *memoryQueueRepository) FindByID(id uuid.UUID) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	queue, ok := r.store.data.queues[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &queue, nil
+}
+
+func (r *memoryQueueRepository) FindWithDetails(id uuid.UUID) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	queue, ok := r.store.data.queues[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	queue.Doctor = r.store.data.doctors[queue.DoctorID.String()]
+	queue.Session = r.store.data.sessions[queue.SessionID]
+	queue.Session.User = r.store.data.users[queue.Session.UserID]
+	return &queue, nil
+}
+
+func (r *memoryQueueRepository) FindLatestBySessionID(sessionID uuid.UUID) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var latest *models.Queue
+	for _, queue := range r.store.data.queues {
+		if queue.SessionID == sessionID && (latest == nil || queue.CreatedAt.After(latest.CreatedAt)) {
+			latest = &queue
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *memoryQueueRepository) FindActiveBySessionID(sessionID uuid.UUID) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, queue := range r.store.data.queues {
+		if queue.SessionID == sessionID && queue.Status != models.QueueStatusCancelled {
+			return &queue, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryQueueRepository) FindServing(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var serving *models.Queue
+	for _, queue := range r.filter(QueueFilter{
+		DoctorID:    doctorID,
+		ServiceDate: &serviceDate,
+		Statuses:    []string{models.QueueStatusCalled, models.QueueStatusInConsultation},
+	}) {
+		if serving == nil || calledAfter(queue, *serving) {
+			serving = &queue
+		}
+	}
+	if serving == nil {
+		return nil, ErrNotFound
+	}
+
+	serving.Session = r.store.data.sessions[serving.SessionID]
+	return serving, nil
+}
+
+// calledAfter reports whether a was called after b, entries never called sort first like NULLs in Postgres
+func calledAfter(a models.Queue, b models.Queue) bool {
+	switch {
+	case a.CalledAt == nil:
+		return b.CalledAt != nil
+	case b.CalledAt == nil:
+		return false
+	default:
+		return a.CalledAt.After(*b.CalledAt)
+	}
+}
+
+func (r *memoryQueueRepository) FindNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	queue, err := r.nextWaiting(doctorID, serviceDate)
+	if err != nil {
+		return nil, err
+	}
+	queue.Session = r.store.data.sessions[queue.SessionID]
+	return queue, nil
+}
+
+// nextWaiting returns the doctor's next waiting entry, the caller holds the lock
+func (r *memoryQueueRepository) nextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	waiting := r.filter(QueueFilter{DoctorID: doctorID, ServiceDate: &serviceDate, Statuses: []string{models.QueueStatusWaiting}})
+	if len(waiting) == 0 {
+		return nil, ErrNotFound
+	}
+	return &waiting[0], nil
+}
+
+// filter returns the matching entries in serving order, the caller holds the lock
+func (r *memoryQueueRepository) filter(filter QueueFilter) []models.Queue {
+	var queues []models.Queue
+	for _, queue := range r.store.data.queues {
+		if filter.DoctorID != uuid.Nil && queue.DoctorID != filter.DoctorID {
+			continue
+		}
+		if filter.ServiceDate != nil && !sameDate(queue.ServiceDate, *filter.ServiceDate) {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, queue.Status) {
+			continue
+		}
+		if filter.BeforeNumber > 0 && queue.Number >= filter.BeforeNumber {
+			continue
+		}
+		if filter.Priority != nil && queue.Priority != *filter.Priority {
+			continue
+		}
+		if filter.NotReminded && queue.ReminderSentAt != nil {
+			continue
+		}
+		if filter.CreatedFrom != nil && queue.CreatedAt.Before(*filter.CreatedFrom) {
+			continue
+		}
+		if filter.CreatedBefore != nil && !queue.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		queues = append(queues, queue)
+	}
+
+	slices.SortFunc(queues, func(a, b models.Queue) int {
+		if a.Priority != b.Priority {
+			if a.Priority {
+				return -1
+			}
+			return 1
+		}
+		return a.Number - b.Number
+	})
+	return queues
+}
+
+func (r *memoryQueueRepository) Find(filter QueueFilter) ([]models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.filter(filter), nil
+}
+
+func (r *memoryQueueRepository) Count(filter QueueFilter) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return len(r.filter(filter)), nil
+}
+
+// LockNextWaiting does not need a lock in memory, transactions are serialized
+func (r *memoryQueueRepository) LockNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.nextWaiting(doctorID, serviceDate)
+}
+
+// LockByID does not need a lock in memory, transactions are serialized
+func (r *memoryQueueRepository) LockByID(id uuid.UUID, doctorID uuid.UUID) (*models.Queue, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	queue, ok := r.store.data.queues[id]
+	if !ok || queue.DoctorID != doctorID {
+		return nil, ErrNotFound
+	}
+	return &queue, nil
+}
+
+func (r *memoryQueueRepository) AverageConsultationDuration(doctorID uuid.UUID, sampleSize int) (time.Duration, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var done []models.Queue
+	for _, queue := range r.store.data.queues {
+		if queue.DoctorID == doctorID && queue.Status == models.QueueStatusDone &&
+			queue.CalledAt != nil && queue.CompletedAt != nil && queue.CompletedAt.After(*queue.CalledAt) {
+			done = append(done, queue)
+		}
+	}
+	if len(done) == 0 {
+		return 0, nil
+	}
+
+	// the most recent consultations first
+	slices.SortFunc(done, func(a, b models.Queue) int {
+		return b.CompletedAt.Compare(*a.CompletedAt)
+	})
+	if len(done) > sampleSize {
+		done = done[:sampleSize]
+	}
+
+	var total time.Duration
+	for _, queue := range done {
+		total += queue.CompletedAt.Sub(*queue.CalledAt)
+	}
+	return total / time.Duration(len(done)), nil
+}
+
+func (r *memoryQueueRepository) DoctorIDsWithPendingReminders(serviceDate time.Time) ([]uuid.UUID, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var doctorIDs []uuid.UUID
+	for _, queue := range r.filter(QueueFilter{ServiceDate: &serviceDate, Statuses: []string{models.QueueStatusWaiting}, NotReminded: true}) {
+		if !slices.Contains(doctorIDs, queue.DoctorID) {
+			doctorIDs = append(doctorIDs, queue.DoctorID)
+		}
+	}
+	return doctorIDs, nil
+}
+
+func (r *memoryQueueRepository) ClaimReminder(id uuid.UUID, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	queue, ok := r.store.data.queues[id]
+	if !ok || queue.Status != models.QueueStatusWaiting || queue.ReminderSentAt != nil {
+		return false, nil
+	}
+	queue.ReminderSentAt = &at
+	r.store.data.queues[id] = queue
+	return true, nil
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryScheduleRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryScheduleRepository) FindShifts(doctorID uuid.UUID) ([]models.DoctorShift, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var shifts []models.DoctorShift
+	for _, shift := range r.store.data.shifts {
+		if doctorID == uuid.Nil || shift.DoctorID == doctorID {
+			shifts = append(shifts, shift)
+		}
+	}
+	slices.SortFunc(shifts, func(a, b models.DoctorShift) int {
+		return cmp.Or(a.Weekday-b.Weekday, cmp.Compare(a.StartTime, b.StartTime))
+	})
+	return shifts, nil
+}
+
+func (r *memoryScheduleRepository) ReplaceShifts(doctorID uuid.UUID, shifts []models.DoctorShift) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, shift := range r.store.data.shifts {
+		if shift.DoctorID == doctorID {
+			delete(r.store.data.shifts, id)
+		}
+	}
+	for i := range shifts {
+		if shifts[i].ID == uuid.Nil {
+			shifts[i].ID = uuid.New()
+		}
+		r.store.data.shifts[shifts[i].ID] = shifts[i]
+	}
+	return nil
+}
+
+// inRange reports whether an entry spanning the given days matches the filter
+func (filter ScheduleFilter) inRange(doctorID uuid.UUID, start time.Time, end time.Time) bool {
+	if filter.DoctorID != uuid.Nil && doctorID != filter.DoctorID {
+		return false
+	}
+	if filter.From != nil && compareDates(end, *filter.From) < 0 {
+		return false
+	}
+	if filter.To != nil && compareDates(start, *filter.To) > 0 {
+		return false
+	}
+	return true
+}
+
+func (r *memoryScheduleRepository) FindExceptions(filter ScheduleFilter) ([]models.DoctorScheduleException, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var exceptions []models.DoctorScheduleException
+	for _, exception := range r.store.data.scheduleExceptions {
+		if filter.inRange(exception.DoctorID, exception.Date, exception.Date) {
+			exceptions = append(exceptions, exception)
+		}
+	}
+	slices.SortFunc(exceptions, func(a, b models.DoctorScheduleException) int {
+		return cmp.Or(compareDates(a.Date, b.Date), cmp.Compare(a.StartTime, b.StartTime))
+	})
+	return exceptions, nil
+}
+
+func (r *memoryScheduleRepository) CreateException(exception *models.DoctorScheduleException) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if exception.ID == uuid.Nil {
+		exception.ID = uuid.New()
+	}
+	r.store.data.scheduleExceptions[exception.ID] = *exception
+	return nil
+}
+
+func (r *memoryScheduleRepository) DeleteException(doctorID uuid.UUID, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	exception, ok := r.store.data.scheduleExceptions[id]
+	if !ok || exception.DoctorID != doctorID {
+		return ErrNotFound
+	}
+	delete(r.store.data.scheduleExceptions, id)
+	return nil
+}
+
+func (r *memoryScheduleRepository) FindLeaves(filter ScheduleFilter) ([]models.DoctorLeave, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var leaves []models.DoctorLeave
+	for _, leave := range r.store.data.leaves {
+		if filter.inRange(leave.DoctorID, leave.StartDate, leave.EndDate) {
+			leaves = append(leaves, leave)
+		}
+	}
+	slices.SortFunc(leaves, func(a, b models.DoctorLeave) int {
+		return compareDates(a.StartDate, b.StartDate)
+	})
+	return leaves, nil
+}
+
+func (r *memoryScheduleRepository) CreateLeave(leave *models.DoctorLeave) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if leave.ID == uuid.Nil {
+		leave.ID = uuid.New()
+	}
+	r.store.data.leaves[leave.ID] = *leave
+	return nil
+}
+
+func (r *memoryScheduleRepository) DeleteLeave(doctorID uuid.UUID, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	leave, ok := r.store.data.leaves[id]
+	if !ok || leave.DoctorID != doctorID {
+		return ErrNotFound
+	}
+	delete(r.store.data.leaves, id)
+	return nil
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memorySessionRepository struct {
+	store *MemoryStore
+}
+
+func (r *memorySessionRepository) FindByID(id uuid.UUID) (*models.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.data.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepository) FindWithMessages(id uuid.UUID) (*models.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.data.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session.User = r.store.data.users[session.UserID]
+	session.Messages = sessionMessages(r.store.data, id)
+	return &session, nil
+}
+
+func (r *memorySessionRepository) FindByUserID(userID uuid.UUID) ([]models.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var sessions []models.Session
+	for _, session := range r.store.data.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b models.Session) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) Create(session *models.Session) error {
+	return r.Save(session)
+}
+
+func (r *memorySessionRepository) Save(session *models.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+
+	stored := *session
+	stored.User = models.User{}
+	stored.Messages = nil
+	r.store.data.sessions[session.ID] = stored
+	return nil
+}
+
+func (r *memorySessionRepository) MarkEmergencyAlerted(id uuid.UUID, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.data.sessions[id]
+	if !ok || session.EmergencyAlertedAt != nil {
+		return false, nil
+	}
+	session.EmergencyAlertedAt = &at
+	r.store.data.sessions[id] = session
+	return true, nil
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"cmp"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps the models in memory, so the service logic can run without Postgres.
+// Transactions are serialized and rolled back by restoring a snapshot, writes made outside a
+// transaction while one is running are lost if it rolls back.
+type MemoryStore struct {
+	mu   *sync.Mutex // guards data
+	txMu *sync.Mutex // serializes transactions
+	data *memoryData
+	inTx bool
+}
+
+type queueCounterKey struct {
+	doctorID    uuid.UUID
+	serviceDate string
+}
+
+type memoryData struct {
+	users    map[uuid.UUID]models.User
+	sessions map[uuid.UUID]models.Session
+	messages map[uuid.UUID]models.Message
+	doctors  map[string]models.Doctor
+	admins   map[uuid.UUID]models.Admin
+	queues   map[uuid.UUID]models.Queue
+	counters map[queueCounterKey]int
+	outbox   map[uuid.UUID]models.OutboxMessage
+
+	idempotencyKeys    map[uuid.UUID]models.IdempotencyKey
+	shifts             map[uuid.UUID]models.DoctorShift
+	scheduleExceptions map[uuid.UUID]models.DoctorScheduleException
+	leaves             map[uuid.UUID]models.DoctorLeave
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu:   &sync.Mutex{},
+		txMu: &sync.Mutex{},
+		data: &memoryData{
+			users:    map[uuid.UUID]models.User{},
+			sessions: map[uuid.UUID]models.Session{},
+			messages: map[uuid.UUID]models.Message{},
+			doctors:  map[string]models.Doctor{},
+			admins:   map[uuid.UUID]models.Admin{},
+			queues:   map[uuid.UUID]models.Queue{},
+			counters: map[queueCounterKey]int{},
+			outbox:   map[uuid.UUID]models.OutboxMessage{},
+
+			idempotencyKeys:    map[uuid.UUID]models.IdempotencyKey{},
+			shifts:             map[uuid.UUID]models.DoctorShift{},
+			scheduleExceptions: map[uuid.UUID]models.DoctorScheduleException{},
+			leaves:             map[uuid.UUID]models.DoctorLeave{},
+		},
+	}
+}
+
+func (s *MemoryStore) Users() UserRepository       { return &memoryUserRepository{store: s} }
+func (s *MemoryStore) Sessions() SessionRepository { return &memorySessionRepository{store: s} }
+func (s *MemoryStore) Messages() MessageRepository { return &memoryMessageRepository{store: s} }
+func (s *MemoryStore) Doctors() DoctorRepository   { return &memoryDoctorRepository{store: s} }
+func (s *MemoryStore) Admins() AdminRepository     { return &memoryAdminRepository{store: s} }
+func (s *MemoryStore) Queues() QueueRepository     { return &memoryQueueRepository{store: s} }
+func (s *MemoryStore) Outbox() OutboxRepository    { return &memoryOutboxRepository{store: s} }
+func (s *MemoryStore) IdempotencyKeys() IdempotencyRepository {
+	return &memoryIdempotencyRepository{store: s}
+}
+
+func (s *MemoryStore) Schedules() ScheduleRepository {
+	return &memoryScheduleRepository{store: s}
+}
+
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	// nested transactions are part of the outer one
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	err := fn(&MemoryStore{mu: s.mu, txMu: s.txMu, data: s.data, inTx: true})
+	if err != nil {
+		s.mu.Lock()
+		*s.data = snapshot
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// clone copies the maps, the models are stored by value so this is a full snapshot
+func (d *memoryData) clone() memoryData {
+	return memoryData{
+		users:    maps.Clone(d.users),
+		sessions: maps.Clone(d.sessions),
+		messages: maps.Clone(d.messages),
+		doctors:  maps.Clone(d.doctors),
+		admins:   maps.Clone(d.admins),
+		queues:   maps.Clone(d.queues),
+		counters: maps.Clone(d.counters),
+		outbox:   maps.Clone(d.outbox),
+
+		idempotencyKeys:    maps.Clone(d.idempotencyKeys),
+		shifts:             maps.Clone(d.shifts),
+		scheduleExceptions: maps.Clone(d.scheduleExceptions),
+		leaves:             maps.Clone(d.leaves),
+	}
+}
+
+// sameDate reports whether both times fall on the same calendar date in their own location,
+// the in-memory equivalent of comparing date columns
+func sameDate(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// compareDates compares the calendar dates of both times in their own location, like comparing date columns
+func compareDates(a time.Time, b time.Time) int {
+	return cmp.Compare(a.Format(time.DateOnly), b.Format(time.DateOnly))
+}
+
+var _ Store = (*MemoryStore)(nil)
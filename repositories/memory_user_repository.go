@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryUserRepository) FindByID(id uuid.UUID) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(email string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.data.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	return r.put(user)
+}
+
+func (r *memoryUserRepository) Save(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	return r.put(user)
+}
+
+// put stores the user without its associations, the caller holds the lock
+func (r *memoryUserRepository) put(user *models.User) error {
+	for id, other := range r.store.data.users {
+		if id != user.ID && other.Email == user.Email {
+			return fmt.Errorf("%w: email %s", ErrDuplicate, user.Email)
+		}
+	}
+
+	stored := *user
+	stored.Sessions = nil
+	r.store.data.users[user.ID] = stored
+	return nil
+}
+
+func (r *memoryUserRepository) IncrementFailedOTPAttempts(id uuid.UUID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	user.OTPFailedAttempts++
+	r.store.data.users[id] = user
+	return user.OTPFailedAttempts, nil
+}
+
+func (r *memoryUserRepository) ResetOTP(id uuid.UUID, lockedUntil *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return nil
+	}
+	user.OTPHash = ""
+	user.OTPIssuedAt = nil
+	user.OTPFailedAttempts = 0
+	user.OTPLockedUntil = lockedUntil
+	r.store.data.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) IncrementFailedContactOTPAttempts(id uuid.UUID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	user.ContactOTPFailedAttempts++
+	r.store.data.users[id] = user
+	return user.ContactOTPFailedAttempts, nil
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type MessageRepository interface {
+	Create(message *models.Message) error
+	// FindBySessionID returns the session's messages in chronological order.
+	FindBySessionID(sessionID uuid.UUID) ([]models.Message, error)
+}
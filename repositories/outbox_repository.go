@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type OutboxRepository interface {
+	Create(message *models.OutboxMessage) error
+	Save(message *models.OutboxMessage) error
+	FindByID(id uuid.UUID) (*models.OutboxMessage, error)
+
+	// LockDue locks pending messages that are due, oldest first, skipping messages locked by other transactions.
+	LockDue(now time.Time, limit int) ([]models.OutboxMessage, error)
+	// List returns messages newest first, optionally filtered by status.
+	List(status string, limit int) ([]models.OutboxMessage, error)
+	CountByStatus() (map[string]int64, error)
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+// QueueFilter selects queue entries, zero fields match everything.
+type QueueFilter struct {
+	DoctorID      uuid.UUID
+	ServiceDate   *time.Time
+	Statuses      []string
+	BeforeNumber  int        // only entries with a lower number
+	Priority      *bool      // only priority entries if true, only regular ones if false
+	NotReminded   bool       // only entries without a reminder
+	CreatedFrom   *time.Time // only entries created at or after this time
+	CreatedBefore *time.Time // only entries created before this time
+}
+
+type QueueRepository interface {
+	// AllocateNumber atomically hands out the doctor's next queue number for the service date.
+	AllocateNumber(doctorID uuid.UUID, serviceDate time.Time) (int, error)
+	Create(queue *models.Queue) error
+	Save(queue *models.Queue) error
+
+	FindByID(id uuid.UUID) (*models.Queue, error)
+	// FindWithDetails returns the entry with its doctor and its session's user.
+	FindWithDetails(id uuid.UUID) (*models.Queue, error)
+	// FindLatestBySessionID returns the session's most recent entry.
+	FindLatestBySessionID(sessionID uuid.UUID) (*models.Queue, error)
+	// FindActiveBySessionID returns the session's entry that has not been cancelled, a session has at most one.
+	FindActiveBySessionID(sessionID uuid.UUID) (*models.Queue, error)
+	// FindServing returns the doctor's most recently called entry that is not finished yet, with its session.
+	FindServing(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error)
+	// FindNextWaiting returns the doctor's next waiting entry, with its session. Priority entries come first.
+	FindNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error)
+	// Find returns the matching entries in serving order, priority entries first and then by number.
+	Find(filter QueueFilter) ([]models.Queue, error)
+	Count(filter QueueFilter) (int, error)
+
+	// LockNextWaiting locks the doctor's next waiting entry, priority entries first, skipping entries locked by other transactions.
+	LockNextWaiting(doctorID uuid.UUID, serviceDate time.Time) (*models.Queue, error)
+	// LockByID locks one of the doctor's entries for update.
+	LockByID(id uuid.UUID, doctorID uuid.UUID) (*models.Queue, error)
+
+	// AverageConsultationDuration averages the time between calling and completing the doctor's most
+	// recent consultations, it returns 0 without history.
+	AverageConsultationDuration(doctorID uuid.UUID, sampleSize int) (time.Duration, error)
+
+	// DoctorIDsWithPendingReminders returns the doctors with waiting, not yet reminded entries on the date.
+	DoctorIDsWithPendingReminders(serviceDate time.Time) ([]uuid.UUID, error)
+	// ClaimReminder marks a waiting entry as reminded, it returns false if it already was or is no longer waiting.
+	ClaimReminder(id uuid.UUID, at time.Time) (bool, error)
+}
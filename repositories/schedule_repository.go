@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+// ScheduleFilter selects schedule exceptions and leave, zero fields match everything.
+type ScheduleFilter struct {
+	DoctorID uuid.UUID
+	From     *time.Time // only entries that end on or after this date
+	To       *time.Time // only entries that start on or before this date
+}
+
+type ScheduleRepository interface {
+	// FindShifts returns the doctor's weekly shifts ordered by weekday and start time,
+	// or every doctor's if doctorID is uuid.Nil.
+	FindShifts(doctorID uuid.UUID) ([]models.DoctorShift, error)
+	// ReplaceShifts deletes the doctor's weekly shifts and stores the given ones instead.
+	ReplaceShifts(doctorID uuid.UUID, shifts []models.DoctorShift) error
+
+	// FindExceptions returns the matching exceptions ordered by date and start time.
+	FindExceptions(filter ScheduleFilter) ([]models.DoctorScheduleException, error)
+	CreateException(exception *models.DoctorScheduleException) error
+	// DeleteException deletes one of the doctor's exceptions, it returns ErrNotFound if the doctor has no such exception.
+	DeleteException(doctorID uuid.UUID, id uuid.UUID) error
+
+	// FindLeaves returns the matching leave ordered by start date.
+	FindLeaves(filter ScheduleFilter) ([]models.DoctorLeave, error)
+	CreateLeave(leave *models.DoctorLeave) error
+	// DeleteLeave deletes one of the doctor's leave entries, it returns ErrNotFound if the doctor has no such entry.
+	DeleteLeave(doctorID uuid.UUID, id uuid.UUID) error
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	FindByID(id uuid.UUID) (*models.Session, error)
+	// FindWithMessages returns the session with its user and its messages in chronological order.
+	FindWithMessages(id uuid.UUID) (*models.Session, error)
+	// FindByUserID returns the user's sessions, newest first.
+	FindByUserID(userID uuid.UUID) ([]models.Session, error)
+	Create(session *models.Session) error
+	Save(session *models.Session) error
+
+	// MarkEmergencyAlerted atomically sets the session's emergency alert time unless it is already set,
+	// reporting whether it did.
+	MarkEmergencyAlerted(id uuid.UUID, at time.Time) (bool, error)
+}
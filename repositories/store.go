@@ -0,0 +1,28 @@
+// Package repositories hides how models are persisted from the services.
+// Every repository has a GORM implementation backed by Postgres and an in-memory one for running the
+// service logic without a database.
+package repositories
+
+import "errors"
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Store gives access to the repositories and runs work in a transaction.
+type Store interface {
+	Users() UserRepository
+	Sessions() SessionRepository
+	Messages() MessageRepository
+	Doctors() DoctorRepository
+	Admins() AdminRepository
+	Queues() QueueRepository
+	Outbox() OutboxRepository
+	IdempotencyKeys() IdempotencyRepository
+	Schedules() ScheduleRepository
+
+	// Transaction runs fn with a store whose repositories all share one transaction.
+	// The transaction is committed if fn returns nil and rolled back otherwise.
+	Transaction(fn func(tx Store) error) error
+}
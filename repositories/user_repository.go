@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+type UserRepository interface {
+	FindByID(id uuid.UUID) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	Create(user *models.User) error
+	Save(user *models.User) error
+
+	// IncrementFailedOTPAttempts atomically adds a failed attempt and returns the new count.
+	IncrementFailedOTPAttempts(id uuid.UUID) (int, error)
+	// ResetOTP clears the user's OTP code and failed attempts, and sets the lockout (nil for none).
+	ResetOTP(id uuid.UUID, lockedUntil *time.Time) error
+	// IncrementFailedContactOTPAttempts atomically adds a failed attempt at verifying a phone number change and
+	// returns the new count.
+	IncrementFailedContactOTPAttempts(id uuid.UUID) (int, error)
+}
@@ -0,0 +1,10 @@
+package schemas
+
+type ContactChangeInput struct {
+	Channel string `json:"channel" validate:"required,oneof=email sms whatsapp"`
+	Phone   string `json:"phone" validate:"omitempty,e164"` // defaults to the stored number
+}
+
+type ContactVerifyInput struct {
+	OTP string `json:"otp" validate:"required"`
+}
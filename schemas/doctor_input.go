@@ -0,0 +1,15 @@
+package schemas
+
+type DoctorInput struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Email     string `json:"email" validate:"required,email,max=100"`
+	Specialty string `json:"specialty" validate:"required,max=100"`
+	Roomno    string `json:"roomno" validate:"required,max=10"`
+
+	DailyCapacity int `json:"daily_capacity" validate:"min=0"` // 0 falls back to DOCTOR_DAILY_CAPACITY
+}
+
+type CreateDoctorInput struct {
+	DoctorInput
+	Password string `json:"password" validate:"omitempty,min=8"` // optional, the doctor cannot log in until one is set
+}
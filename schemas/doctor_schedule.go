@@ -0,0 +1,35 @@
+package schemas
+
+import "github.com/Om-SEHAT/omsehat-api/models"
+
+// DoctorSchedule is a doctor's weekly shifts with their exceptions and leave from today on.
+type DoctorSchedule struct {
+	Shifts     []models.DoctorShift             `json:"shifts"`
+	Exceptions []models.DoctorScheduleException `json:"exceptions"`
+	Leaves     []models.DoctorLeave             `json:"leaves"`
+}
+
+type ShiftInput struct {
+	Weekday   int    `json:"weekday" validate:"min=0,max=6"` // 0 is Sunday
+	StartTime string `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime   string `json:"end_time" validate:"required,datetime=15:04"`
+	Capacity  int    `json:"capacity" validate:"min=0"` // 0 is unlimited
+}
+
+type SetShiftsInput struct {
+	Shifts []ShiftInput `json:"shifts" validate:"dive"` // an empty list removes the weekly schedule
+}
+
+type ScheduleExceptionInput struct {
+	Date      string `json:"date" validate:"required,datetime=2006-01-02"`
+	StartTime string `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime   string `json:"end_time" validate:"required,datetime=15:04"`
+	Capacity  int    `json:"capacity" validate:"min=0"` // 0 is unlimited
+	Reason    string `json:"reason" validate:"max=255"`
+}
+
+type LeaveInput struct {
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+	Reason    string `json:"reason" validate:"max=255"`
+}
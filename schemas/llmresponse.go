@@ -1,8 +1,15 @@
 package schemas
 
 type LLMResponse struct {
-	NextAction string `json:"next_action"`
-	Reply      string `json:"reply"`
-	DoctorID  string `json:"doctor_id"`
+	NextAction   string `json:"next_action"`
+	Reply        string `json:"reply"`
+	DoctorID     string `json:"doctor_id"`
+	Specialty    string `json:"specialty"` // the specialty the patient should see, used when doctor_id is not a valid doctor
 	PreDiagnosis string `json:"prediagnosis"`
-}
\ No newline at end of file
+	Severity     string `json:"severity"` // low, medium, high or emergency
+
+	// set by the server once the doctor has been checked, see the QueueAssignment paths
+	AssignmentPath string `json:"-"`
+	// set by the server when the patient was triaged as an emergency
+	TriageReason string `json:"-"`
+}
@@ -0,0 +1,10 @@
+package schemas
+
+type LoginInput struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type SetPasswordInput struct {
+	Password string `json:"password" validate:"required,min=8"`
+}
@@ -0,0 +1,33 @@
+package schemas
+
+import "time"
+
+// Notification kinds
+const (
+	NotificationKindOTP   = "otp"
+	NotificationKindQueue = "queue"
+	// NotificationKindQueueNear tells a patient their turn is coming up
+	NotificationKindQueueNear = "queue_near"
+	// NotificationKindStaffAlert tells clinic staff about an emergency patient
+	NotificationKindStaffAlert = "staff_alert"
+)
+
+// Notification channels
+const (
+	NotificationChannelEmail    = "email"
+	NotificationChannelSMS      = "sms"
+	NotificationChannelWhatsApp = "whatsapp"
+)
+
+// Notification is a message to a patient, independent of how it is delivered.
+type Notification struct {
+	Kind    string `json:"kind"`
+	Channel string `json:"channel"`
+	To      string `json:"to"` // email address or phone number, depending on the channel
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+
+	// ExpiresAt is when the message becomes useless, such as an OTP code, it is not delivered after that
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
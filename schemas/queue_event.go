@@ -0,0 +1,28 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueueEvent is pushed to queue board subscribers whenever a ticket changes.
+// It only carries ticket numbers and statuses, never patient data.
+type QueueEvent struct {
+	Type     string    `json:"type"`
+	DoctorID uuid.UUID `json:"doctor_id"`
+	QueueID  uuid.UUID `json:"queue_id"`
+	Number   int       `json:"number"`
+	Status   string    `json:"status"`
+	At       time.Time `json:"at"`
+}
+
+// QueueBoardEntry is the state of one doctor's queue shown on the board.
+type QueueBoardEntry struct {
+	DoctorID   string `json:"doctor_id"`
+	DoctorName string `json:"doctor_name"`
+	Specialty  string `json:"specialty"`
+	Roomno     string `json:"roomno"`
+	NowServing *int   `json:"now_serving"` // nil if nobody has been called today
+	Waiting    int    `json:"waiting"`
+}
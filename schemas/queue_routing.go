@@ -0,0 +1,13 @@
+package schemas
+
+import "github.com/google/uuid"
+
+// QueueRouting explains why a ticket went to another doctor or day than the one the LLM chose,
+// or why no ticket could be handed out.
+type QueueRouting struct {
+	Reason            string     `json:"reason"`
+	Message           string     `json:"message"`
+	RequestedDoctorID *uuid.UUID `json:"requested_doctor_id,omitempty"`
+	DoctorID          *uuid.UUID `json:"doctor_id,omitempty"`
+	ServiceDate       string     `json:"service_date,omitempty"`
+}
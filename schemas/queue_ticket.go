@@ -0,0 +1,11 @@
+package schemas
+
+import "github.com/google/uuid"
+
+// QueueTicket is a ticket as patients see it, its number and estimated wait without any patient data.
+type QueueTicket struct {
+	ID                   uuid.UUID `json:"id"`
+	Number               int       `json:"number"`
+	Status               string    `json:"status"`
+	EstimatedWaitMinutes *int      `json:"estimated_wait_minutes,omitempty"`
+}
@@ -10,4 +10,7 @@ type RegisterUserInput struct {
 	Heartrate   float32 `json:"heartrate" validate:"required"`
 	Bodytemp    float32 `json:"bodytemp" validate:"required"`
 	Gender      string  `json:"gender" validate:"required"`
+	Language    string  `json:"language" validate:"omitempty,oneof=id en"`
+	Phone       string  `json:"phone" validate:"omitempty,e164"`
+	OTPChannel  string  `json:"otp_channel" validate:"omitempty,oneof=email sms whatsapp"`
 }
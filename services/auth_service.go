@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// AuthService logs staff in and refreshes the tokens of every role.
+type AuthService struct {
+	store repositories.Store
+}
+
+func NewAuthService(store repositories.Store) *AuthService {
+	return &AuthService{store: store}
+}
+
+type AuthClaims struct {
+	Role      string `json:"role"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET is not configured")
+	}
+	return []byte(secret), nil
+}
+
+func accessTokenTTL() time.Duration {
+	return config.GetEnvDuration("JWT_ACCESS_TTL", 15*time.Minute)
+}
+
+func refreshTokenTTL() time.Duration {
+	return config.GetEnvDuration("JWT_REFRESH_TTL", 7*24*time.Hour)
+}
+
+func signToken(subject uuid.UUID, role string, tokenType string, ttl time.Duration) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := AuthClaims{
+		Role:      role,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   subject.String(),
+			Issuer:    "omsehat-api",
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+// IssueTokens creates a signed access and refresh token pair for the given subject and role.
+func IssueTokens(subject uuid.UUID, role string) (*schemas.AuthTokens, error) {
+	accessTTL := accessTokenTTL()
+
+	accessToken, err := signToken(subject, role, TokenTypeAccess, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := signToken(subject, role, TokenTypeRefresh, refreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemas.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// ParseToken verifies the token signature and expiry, and makes sure it is of the expected type.
+func ParseToken(tokenString string, expectedType string) (*AuthClaims, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var claims AuthClaims
+	_, err = jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer("omsehat-api"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.TokenType != expectedType {
+		return nil, fmt.Errorf("%w: unexpected token type %q", ErrInvalidToken, claims.TokenType)
+	}
+
+	return &claims, nil
+}
+
+// RefreshTokens exchanges a valid refresh token for a new token pair.
+func (s *AuthService) RefreshTokens(refreshToken string) (*schemas.AuthTokens, error) {
+	claims, err := ParseToken(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+
+	// make sure the account still exists before issuing new tokens
+	switch claims.Role {
+	case models.RolePatient:
+		_, err = s.store.Users().FindByID(subject)
+	case models.RoleDoctor:
+		var doctor *models.Doctor
+		doctor, err = s.store.Doctors().FindByID(subject)
+		if err == nil && !doctor.IsActive() {
+			err = repositories.ErrNotFound
+		}
+	case models.RoleAdmin:
+		_, err = s.store.Admins().FindByID(subject)
+	default:
+		err = repositories.ErrNotFound
+	}
+
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("%w: account not found", ErrInvalidToken)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
+	}
+
+	return IssueTokens(subject, claims.Role)
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// AvailabilityService keeps the doctors' schedules and tells which doctors are on shift at a given time.
+// Doctors without weekly shifts are treated as always on shift, so doctors nobody has scheduled yet keep
+// taking patients.
+type AvailabilityService struct {
+	store repositories.Store
+}
+
+func NewAvailabilityService(store repositories.Store) *AvailabilityService {
+	return &AvailabilityService{store: store}
+}
+
+// AvailableDoctors returns the active doctors on shift at the given time ordered by name.
+func (s *AvailabilityService) AvailableDoctors(at time.Time) ([]models.Doctor, error) {
+	return availableDoctors(s.store, at)
+}
+
+func availableDoctors(store repositories.Store, at time.Time) ([]models.Doctor, error) {
+	onShift, err := onShiftDoctors(store, at)
+	if err != nil {
+		return nil, err
+	}
+
+	doctors := make([]models.Doctor, 0, len(onShift))
+	for _, candidate := range onShift {
+		doctors = append(doctors, candidate.doctor)
+	}
+	return doctors, nil
+}
+
+// onShiftDoctor is an active doctor on shift and the working hours they are in
+type onShiftDoctor struct {
+	doctor models.Doctor
+	id     uuid.UUID
+	window shiftWindow
+}
+
+// onShiftDoctors returns the active doctors on shift at the given time ordered by name
+func onShiftDoctors(store repositories.Store, at time.Time) ([]onShiftDoctor, error) {
+	doctors, err := store.Doctors().FindActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch doctors: %w", err)
+	}
+
+	day, err := loadDaySchedule(store, uuid.Nil, at)
+	if err != nil {
+		return nil, err
+	}
+
+	var onShift []onShiftDoctor
+	for _, doctor := range doctors {
+		doctorID, err := uuid.Parse(doctor.ID)
+		if err != nil {
+			continue
+		}
+		if window, ok := day.window(doctorID, at); ok {
+			onShift = append(onShift, onShiftDoctor{doctor: doctor, id: doctorID, window: window})
+		}
+	}
+	return onShift, nil
+}
+
+// daySchedule holds the schedule entries that apply to one clinic day
+type daySchedule struct {
+	scheduled  map[uuid.UUID]bool // doctors with weekly shifts on any day
+	shifts     map[uuid.UUID][]models.DoctorShift
+	exceptions map[uuid.UUID][]models.DoctorScheduleException
+	onLeave    map[uuid.UUID]bool
+}
+
+// loadDaySchedule loads the schedule of the clinic day the time falls on, for one doctor or every doctor
+// if doctorID is uuid.Nil
+func loadDaySchedule(store repositories.Store, doctorID uuid.UUID, at time.Time) (*daySchedule, error) {
+	date := utils.ServiceDate(at)
+	filter := repositories.ScheduleFilter{DoctorID: doctorID, From: &date, To: &date}
+
+	shifts, err := store.Schedules().FindShifts(doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shifts: %w", err)
+	}
+	exceptions, err := store.Schedules().FindExceptions(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule exceptions: %w", err)
+	}
+	leaves, err := store.Schedules().FindLeaves(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leave: %w", err)
+	}
+
+	day := &daySchedule{
+		scheduled:  map[uuid.UUID]bool{},
+		shifts:     map[uuid.UUID][]models.DoctorShift{},
+		exceptions: map[uuid.UUID][]models.DoctorScheduleException{},
+		onLeave:    map[uuid.UUID]bool{},
+	}
+	for _, shift := range shifts {
+		day.scheduled[shift.DoctorID] = true
+		if time.Weekday(shift.Weekday) == date.Weekday() {
+			day.shifts[shift.DoctorID] = append(day.shifts[shift.DoctorID], shift)
+		}
+	}
+	for _, exception := range exceptions {
+		day.exceptions[exception.DoctorID] = append(day.exceptions[exception.DoctorID], exception)
+	}
+	for _, leave := range leaves {
+		day.onLeave[leave.DoctorID] = true
+	}
+	return day, nil
+}
+
+// shiftWindow is a period a doctor works in on one day
+type shiftWindow struct {
+	start    string // HH:MM, inclusive
+	end      string // HH:MM, exclusive
+	capacity int    // most tickets handed out during the period, 0 is unlimited
+}
+
+// allDay is the window of doctors without weekly shifts
+var allDay = shiftWindow{start: "00:00", end: "24:00"}
+
+// window returns the working hours the doctor is in at the given time, which must fall on the schedule's day.
+// Leave wins over everything, exceptions replace the weekly shifts of their date.
+func (d *daySchedule) window(doctorID uuid.UUID, at time.Time) (shiftWindow, bool) {
+	if d.onLeave[doctorID] {
+		return shiftWindow{}, false
+	}
+
+	clock := at.In(config.ClinicLocation()).Format("15:04")
+	if exceptions, ok := d.exceptions[doctorID]; ok {
+		for _, exception := range exceptions {
+			if withinHours(exception.StartTime, exception.EndTime, clock) {
+				return shiftWindow{start: exception.StartTime, end: exception.EndTime, capacity: exception.Capacity}, true
+			}
+		}
+		return shiftWindow{}, false
+	}
+
+	if !d.scheduled[doctorID] {
+		return allDay, true
+	}
+	for _, shift := range d.shifts[doctorID] {
+		if withinHours(shift.StartTime, shift.EndTime, clock) {
+			return shiftWindow{start: shift.StartTime, end: shift.EndTime, capacity: shift.Capacity}, true
+		}
+	}
+	return shiftWindow{}, false
+}
+
+// worksOn reports whether the doctor works at some point on the schedule's day
+func (d *daySchedule) worksOn(doctorID uuid.UUID) bool {
+	if d.onLeave[doctorID] {
+		return false
+	}
+	if _, ok := d.exceptions[doctorID]; ok {
+		return true
+	}
+	return !d.scheduled[doctorID] || len(d.shifts[doctorID]) > 0
+}
+
+// capacity returns the total capacity of the doctor's working hours on the day, 0 if any of them is unlimited
+func (d *daySchedule) capacity(doctorID uuid.UUID) int {
+	var windows []shiftWindow
+	if exceptions, ok := d.exceptions[doctorID]; ok {
+		for _, exception := range exceptions {
+			windows = append(windows, shiftWindow{capacity: exception.Capacity})
+		}
+	} else if d.scheduled[doctorID] {
+		for _, shift := range d.shifts[doctorID] {
+			windows = append(windows, shiftWindow{capacity: shift.Capacity})
+		}
+	}
+
+	total := 0
+	for _, window := range windows {
+		if window.capacity == 0 {
+			return 0
+		}
+		total += window.capacity
+	}
+	return total
+}
+
+// withinHours reports whether the HH:MM clock time falls between start (inclusive) and end (exclusive)
+func withinHours(start string, end string, clock string) bool {
+	return start <= clock && clock < end
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RequestContactChange sets the channel the user is reached on. Switching to email, or to a phone channel on the
+// number they already verified, applies right away. A new phone number is only used once the user entered the
+// code sent to it, see VerifyContactChange. It reports whether that code was sent.
+func (s *UserService) RequestContactChange(userID uuid.UUID, input schemas.ContactChangeInput) (*models.User, bool, error) {
+	var user *models.User
+	var pending bool
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		user, err = tx.Users().FindByID(userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch user: %w", err)
+		}
+
+		if !s.outbox.ChannelAvailable(input.Channel) {
+			return ErrChannelUnavailable
+		}
+
+		phone := input.Phone
+		if phone == "" && user.Phone != nil {
+			phone = *user.Phone
+		}
+
+		// email and an already verified number need no code
+		if input.Channel == schemas.NotificationChannelEmail ||
+			(user.PhoneVerifiedAt != nil && user.Phone != nil && *user.Phone == phone) {
+			user.PreferredChannel = input.Channel
+			clearContactChange(user)
+			user.UpdatedAt = time.Now()
+			if err := tx.Users().Save(user); err != nil {
+				return fmt.Errorf("failed to update user: %w", err)
+			}
+			return nil
+		}
+
+		if phone == "" {
+			return ErrPhoneRequired
+		}
+
+		// enforce the resend cooldown
+		now := time.Now()
+		if user.ContactOTPIssuedAt != nil {
+			if nextAllowed := user.ContactOTPIssuedAt.Add(otpResendCooldown()); nextAllowed.After(now) {
+				return &OTPRetryError{Err: ErrOTPCooldown, RetryAfter: nextAllowed.Sub(now)}
+			}
+		}
+
+		otp, hash, err := newOTP()
+		if err != nil {
+			return err
+		}
+
+		user.PendingPhone = &phone
+		user.PendingChannel = input.Channel
+		user.ContactOTPHash = hash
+		user.ContactOTPIssuedAt = &now
+		user.ContactOTPFailedAttempts = 0
+		user.UpdatedAt = now
+		if err := tx.Users().Save(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		pending = true
+		return s.enqueueOTP(tx, user, otp, input.Channel, phone)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if pending {
+		s.outbox.Wake()
+	}
+	return user, pending, nil
+}
+
+// VerifyContactChange checks the code sent to the user's new phone number and switches them to it. Too many
+// wrong codes drop the change, which then has to be requested again.
+func (s *UserService) VerifyContactChange(userID uuid.UUID, otp string) (*models.User, error) {
+	user, err := s.store.Users().FindByID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	now := time.Now()
+	if user.PendingPhone == nil || user.ContactOTPHash == "" || user.ContactOTPIssuedAt == nil ||
+		now.After(user.ContactOTPIssuedAt.Add(otpTTL())) {
+		return nil, ErrOTPExpired
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.ContactOTPHash), []byte(otp)) != nil {
+		attempts, err := s.store.Users().IncrementFailedContactOTPAttempts(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record OTP attempt: %w", err)
+		}
+		if attempts < otpMaxAttempts() {
+			return nil, ErrOTPInvalid
+		}
+
+		clearContactChange(user)
+		if err := s.store.Users().Save(user); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil, ErrOTPLocked
+	}
+
+	user.Phone = user.PendingPhone
+	user.PhoneVerifiedAt = &now
+	user.PreferredChannel = user.PendingChannel
+	clearContactChange(user)
+	user.UpdatedAt = now
+	if err := s.store.Users().Save(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// clearContactChange drops the user's pending phone number change and its code
+func clearContactChange(user *models.User) {
+	user.PendingPhone = nil
+	user.PendingChannel = ""
+	user.ContactOTPHash = ""
+	user.ContactOTPIssuedAt = nil
+	user.ContactOTPFailedAttempts = 0
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// checkDoctorChoice makes sure an appointment goes to an active doctor before a ticket is created. If the LLM
+// chose an unknown or deactivated doctor it is asked once more with a correction. If that fails too the least
+// busy doctor on shift of the specialty the LLM named takes the patient, then the least busy general
+// practitioner on shift. The reply is kept as it is, the ticket tells the patient which doctor they got.
+// It sets the response's doctor ID and assignment path, or returns ErrDoctorUnavailable if nobody is left.
+func (s *SessionService) checkDoctorChoice(ctx context.Context, request LLMRequest, response *schemas.LLMResponse) error {
+	if response.NextAction != "APPOINTMENT" {
+		return nil
+	}
+
+	doctors, err := s.store.Doctors().FindActive()
+	if err != nil {
+		return fmt.Errorf("failed to fetch doctors: %w", err)
+	}
+	isActiveDoctor := func(doctorID string) bool {
+		id, err := uuid.Parse(doctorID)
+		if err != nil {
+			return false
+		}
+		for _, doctor := range doctors {
+			if doctor.ID == id.String() {
+				return true
+			}
+		}
+		return false
+	}
+
+	if isActiveDoctor(response.DoctorID) {
+		response.AssignmentPath = models.QueueAssignmentLLM
+		return nil
+	}
+
+	// ask the LLM again, pointing out the mistake
+	log.Printf("LLM chose doctor %q which is not an active doctor, asking again\n", response.DoctorID)
+	request.SystemPrompt += fmt.Sprintf(
+		"\n\nCORRECTION: your previous answer chose doctor_id %q, which is not in the list of available doctors. Answer again and choose a doctor_id exactly as written in the list.",
+		response.DoctorID,
+	)
+	corrected, err := s.llm.GenerateResponse(ctx, request)
+	if err != nil {
+		log.Println("Error asking the LLM to correct the doctor:", err)
+	} else if corrected.NextAction == "APPOINTMENT" && isActiveDoctor(corrected.DoctorID) {
+		response.DoctorID = corrected.DoctorID
+		response.AssignmentPath = models.QueueAssignmentLLMCorrected
+		return nil
+	}
+	if response.Specialty == "" && err == nil {
+		response.Specialty = corrected.Specialty
+	}
+
+	// fall back to the specialty the LLM named, then to a general practitioner
+	now := time.Now()
+	onShift, err := onShiftDoctors(s.store, now)
+	if err != nil {
+		return err
+	}
+
+	specialty := strings.TrimSpace(response.Specialty)
+	fallbacks := []struct {
+		path  string
+		match func(models.Doctor) bool
+	}{
+		{models.QueueAssignmentSpecialtyMatch, func(doctor models.Doctor) bool {
+			return specialty != "" && strings.EqualFold(strings.TrimSpace(doctor.Specialty), specialty)
+		}},
+		{models.QueueAssignmentGeneralPractitioner, isGeneralPractitioner},
+	}
+	for _, fallback := range fallbacks {
+		doctor, err := leastBusyDoctor(s.store, onShift, utils.ServiceDate(now), fallback.match)
+		if err != nil {
+			return err
+		}
+		if doctor != nil {
+			log.Printf("Assigning the patient to doctor %s instead (%s)\n", doctor.id, fallback.path)
+			response.DoctorID = doctor.id.String()
+			response.AssignmentPath = fallback.path
+			return nil
+		}
+	}
+
+	return ErrDoctorUnavailable
+}
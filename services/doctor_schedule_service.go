@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSchedule       = errors.New("invalid schedule")
+	ErrScheduleEntryNotFound = errors.New("schedule entry not found")
+)
+
+// GetSchedule returns the doctor's weekly shifts with the exceptions and leave from today on.
+func (s *AvailabilityService) GetSchedule(doctorID string) (*schemas.DoctorSchedule, error) {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := utils.ServiceDate(time.Now())
+	filter := repositories.ScheduleFilter{DoctorID: id, From: &today}
+
+	shifts, err := s.store.Schedules().FindShifts(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shifts: %w", err)
+	}
+	exceptions, err := s.store.Schedules().FindExceptions(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule exceptions: %w", err)
+	}
+	leaves, err := s.store.Schedules().FindLeaves(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leave: %w", err)
+	}
+
+	// empty lists rather than null in the response
+	schedule := &schemas.DoctorSchedule{
+		Shifts:     append([]models.DoctorShift{}, shifts...),
+		Exceptions: append([]models.DoctorScheduleException{}, exceptions...),
+		Leaves:     append([]models.DoctorLeave{}, leaves...),
+	}
+	return schedule, nil
+}
+
+// SetShifts replaces the doctor's weekly shifts.
+func (s *AvailabilityService) SetShifts(doctorID string, input schemas.SetShiftsInput) ([]models.DoctorShift, error) {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shifts := make([]models.DoctorShift, 0, len(input.Shifts))
+	for _, shiftInput := range input.Shifts {
+		start, end, err := parseHours(shiftInput.StartTime, shiftInput.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, models.DoctorShift{
+			DoctorID:  id,
+			Weekday:   shiftInput.Weekday,
+			StartTime: start,
+			EndTime:   end,
+			Capacity:  shiftInput.Capacity,
+			CreatedAt: now,
+		})
+	}
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		return tx.Schedules().ReplaceShifts(id, shifts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save shifts: %w", err)
+	}
+
+	return shifts, nil
+}
+
+// AddException sets other working hours for the doctor on one date, replacing the weekly shifts of that date.
+func (s *AvailabilityService) AddException(doctorID string, input schemas.ScheduleExceptionInput) (*models.DoctorScheduleException, error) {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseScheduleDate(input.Date)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := parseHours(input.StartTime, input.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	exception := models.DoctorScheduleException{
+		DoctorID:  id,
+		Date:      date,
+		StartTime: start,
+		EndTime:   end,
+		Capacity:  input.Capacity,
+		Reason:    input.Reason,
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.Schedules().CreateException(&exception); err != nil {
+		return nil, fmt.Errorf("failed to save schedule exception: %w", err)
+	}
+
+	return &exception, nil
+}
+
+func (s *AvailabilityService) RemoveException(doctorID string, exceptionID string) error {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return err
+	}
+	entryID, err := uuid.Parse(exceptionID)
+	if err != nil {
+		return ErrScheduleEntryNotFound
+	}
+
+	err = s.store.Schedules().DeleteException(id, entryID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrScheduleEntryNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete schedule exception: %w", err)
+	}
+	return nil
+}
+
+// AddLeave marks the doctor as off for every day from the start date to the end date.
+func (s *AvailabilityService) AddLeave(doctorID string, input schemas.LeaveInput) (*models.DoctorLeave, error) {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, err := parseScheduleDate(input.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := parseScheduleDate(input.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: leave must not end before it starts", ErrInvalidSchedule)
+	}
+
+	leave := models.DoctorLeave{
+		DoctorID:  id,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Reason:    input.Reason,
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.Schedules().CreateLeave(&leave); err != nil {
+		return nil, fmt.Errorf("failed to save leave: %w", err)
+	}
+
+	return &leave, nil
+}
+
+func (s *AvailabilityService) RemoveLeave(doctorID string, leaveID string) error {
+	id, err := s.findDoctorID(doctorID)
+	if err != nil {
+		return err
+	}
+	entryID, err := uuid.Parse(leaveID)
+	if err != nil {
+		return ErrScheduleEntryNotFound
+	}
+
+	err = s.store.Schedules().DeleteLeave(id, entryID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrScheduleEntryNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete leave: %w", err)
+	}
+	return nil
+}
+
+// findDoctorID parses the doctor ID and checks the doctor exists
+func (s *AvailabilityService) findDoctorID(doctorID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(doctorID)
+	if err != nil {
+		return uuid.Nil, ErrDoctorNotFound
+	}
+
+	_, err = s.store.Doctors().FindByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return uuid.Nil, ErrDoctorNotFound
+	} else if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to fetch doctor: %w", err)
+	}
+	return id, nil
+}
+
+// parseHours normalizes the start and end times to HH:MM, so they compare correctly as strings
+func parseHours(startTime string, endTime string) (string, string, error) {
+	start, err := time.Parse("15:04", startTime)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: invalid start time %q", ErrInvalidSchedule, startTime)
+	}
+	end, err := time.Parse("15:04", endTime)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: invalid end time %q", ErrInvalidSchedule, endTime)
+	}
+	if !end.After(start) {
+		return "", "", fmt.Errorf("%w: %s-%s must end after it starts", ErrInvalidSchedule, startTime, endTime)
+	}
+	return start.Format("15:04"), end.Format("15:04"), nil
+}
+
+// parseScheduleDate parses a YYYY-MM-DD date as a day on the clinic's calendar
+func parseScheduleDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation(time.DateOnly, value, config.ClinicLocation())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidSchedule, value)
+	}
+	return date, nil
+}
@@ -1,29 +1,147 @@
 package services
 
 import (
-	"github.com/Om-SEHAT/omsehat-api/config"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/google/uuid"
 )
 
-func GetAllDoctors() []models.Doctor {
-	var doctors []models.Doctor
-	err := config.DB.Find(&doctors).Error
+var (
+	ErrDoctorNotFound    = errors.New("doctor not found")
+	ErrDoctorEmailTaken  = errors.New("a doctor with this email already exists")
+	ErrDoctorUnavailable = errors.New("doctor is not available for new appointments")
+)
+
+type DoctorService struct {
+	store repositories.Store
+}
+
+func NewDoctorService(store repositories.Store) *DoctorService {
+	return &DoctorService{store: store}
+}
+
+// GetAllDoctors returns the active doctors, the ones patients can be assigned to.
+func (s *DoctorService) GetAllDoctors() []models.Doctor {
+	doctors, err := s.store.Doctors().FindActive()
 	if err != nil {
 		return nil
 	}
 	return doctors
 }
 
-func GetDoctorByID(doctorID string) *models.Doctor {
-	var doctor models.Doctor
+// ListDoctors returns every doctor including deactivated ones, for admins.
+func (s *DoctorService) ListDoctors() ([]models.Doctor, error) {
+	doctors, err := s.store.Doctors().FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch doctors: %w", err)
+	}
+	return doctors, nil
+}
+
+func (s *DoctorService) GetDoctorByID(doctorID string) *models.Doctor {
 	id, err := uuid.Parse(doctorID)
 	if err != nil {
 		return nil
 	}
-	err = config.DB.First(&doctor, "id = ?", id).Error
+	doctor, err := s.store.Doctors().FindByID(id)
 	if err != nil {
 		return nil
 	}
-	return &doctor
+	return doctor
+}
+
+func (s *DoctorService) CreateDoctor(input schemas.CreateDoctorInput) (*models.Doctor, error) {
+	doctor := models.Doctor{
+		Name:      input.Name,
+		Email:     input.Email,
+		Specialty: input.Specialty,
+		Roomno:    input.Roomno,
+
+		DailyCapacity: input.DailyCapacity,
+	}
+
+	if input.Password != "" {
+		hash, err := hashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
+		doctor.PasswordHash = hash
+	}
+
+	err := s.store.Doctors().Create(&doctor)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, ErrDoctorEmailTaken
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create doctor: %w", err)
+	}
+
+	return &doctor, nil
+}
+
+func (s *DoctorService) UpdateDoctor(doctorID string, input schemas.DoctorInput) (*models.Doctor, error) {
+	doctor := s.GetDoctorByID(doctorID)
+	if doctor == nil {
+		return nil, ErrDoctorNotFound
+	}
+
+	doctor.Name = input.Name
+	doctor.Email = input.Email
+	doctor.Specialty = input.Specialty
+	doctor.Roomno = input.Roomno
+	doctor.DailyCapacity = input.DailyCapacity
+
+	err := s.store.Doctors().Save(doctor)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, ErrDoctorEmailTaken
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update doctor: %w", err)
+	}
+
+	return doctor, nil
+}
+
+// SetDoctorActive deactivates or reactivates the doctor. Deactivated doctors keep their history but cannot
+// log in, are left out of the LLM prompt and get no new queue tickets.
+func (s *DoctorService) SetDoctorActive(doctorID string, active bool) (*models.Doctor, error) {
+	doctor := s.GetDoctorByID(doctorID)
+	if doctor == nil {
+		return nil, ErrDoctorNotFound
+	}
+
+	if active {
+		doctor.DeactivatedAt = nil
+	} else if doctor.IsActive() {
+		now := time.Now()
+		doctor.DeactivatedAt = &now
+	}
+
+	if err := s.store.Doctors().Save(doctor); err != nil {
+		return nil, fmt.Errorf("failed to update doctor: %w", err)
+	}
+
+	return doctor, nil
+}
+
+func (s *DoctorService) SetDoctorPassword(doctorID string, password string) error {
+	doctor := s.GetDoctorByID(doctorID)
+	if doctor == nil {
+		return ErrDoctorNotFound
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	doctor.PasswordHash = hash
+	if err := s.store.Doctors().Save(doctor); err != nil {
+		return fmt.Errorf("failed to update doctor password: %w", err)
+	}
+
+	return nil
 }
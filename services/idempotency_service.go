@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+const idempotencyCleanupInterval = time.Hour
+
+// IdempotencyService remembers the responses of requests sent with an Idempotency-Key header.
+type IdempotencyService struct {
+	store repositories.Store
+}
+
+func NewIdempotencyService(store repositories.Store) *IdempotencyService {
+	return &IdempotencyService{store: store}
+}
+
+// idempotencyKeyTTL is how long a response is kept for replays
+func idempotencyKeyTTL() time.Duration {
+	return config.GetEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+}
+
+// idempotencyProcessingTimeout is how long a request may hold its key before another attempt can take it over,
+// so a key is not stuck when the instance processing it dies
+func idempotencyProcessingTimeout() time.Duration {
+	return config.GetEnvDuration("IDEMPOTENCY_PROCESSING_TIMEOUT", 2*time.Minute)
+}
+
+// Begin claims the account's key for the request with the given fingerprint.
+// If the request was already completed it returns the stored record and replay is true, otherwise the
+// caller processes the request and hands the returned record to Complete or Release.
+func (s *IdempotencyService) Begin(subject uuid.UUID, key string, fingerprint string) (record *models.IdempotencyKey, replay bool, err error) {
+	now := time.Now()
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		existing, err := tx.IdempotencyKeys().Find(subject, key)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to fetch idempotency key: %w", err)
+		}
+
+		if existing != nil {
+			stale := existing.Status == models.IdempotencyStatusInProgress && now.Sub(existing.UpdatedAt) > idempotencyProcessingTimeout()
+
+			switch {
+			case existing.ExpiresAt.Before(now):
+				// an expired key can be used again
+				if err := tx.IdempotencyKeys().Delete(existing.ID); err != nil {
+					return fmt.Errorf("failed to delete expired idempotency key: %w", err)
+				}
+			case existing.Fingerprint != fingerprint:
+				return ErrIdempotencyKeyReused
+			case existing.Status == models.IdempotencyStatusCompleted:
+				record, replay = existing, true
+				return nil
+			case !stale:
+				return ErrIdempotencyKeyInProgress
+			default:
+				// take over a claim that was abandoned
+				existing.UpdatedAt = now
+				record = existing
+				return tx.IdempotencyKeys().Save(existing)
+			}
+		}
+
+		record = &models.IdempotencyKey{
+			Subject:     subject,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      models.IdempotencyStatusInProgress,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyKeyTTL()),
+		}
+		return tx.IdempotencyKeys().Create(record)
+	})
+
+	// a concurrent request claimed the key between the lookup and the insert
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, false, ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return record, replay, nil
+}
+
+// Complete stores the response of the request, later requests with the same key get it replayed.
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, status int, body string) error {
+	record.Status = models.IdempotencyStatusCompleted
+	record.ResponseStatus = status
+	record.ResponseBody = body
+	record.UpdatedAt = time.Now()
+
+	if err := s.store.IdempotencyKeys().Save(record); err != nil {
+		return fmt.Errorf("failed to save idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release gives up the claim on the key so the request can be retried, used when processing failed.
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	if err := s.store.IdempotencyKeys().Delete(record.ID); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// StartCleanup removes expired keys in the background until ctx is cancelled.
+func (s *IdempotencyService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(idempotencyCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.store.IdempotencyKeys().DeleteExpired(time.Now()); err != nil {
+					log.Printf("Error removing expired idempotency keys: %v\n", err)
+				}
+			}
+		}
+	}()
+}
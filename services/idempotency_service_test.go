@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyReplaysCompletedResponse(t *testing.T) {
+	s := newTestServices(t)
+	subject := uuid.New()
+
+	record, replay, err := s.idempotency.Begin(subject, "key-1", "fingerprint")
+	if err != nil || replay {
+		t.Fatalf("expected a fresh claim, got replay %v and error %v", replay, err)
+	}
+
+	// a retry while the first request is running must wait for it
+	if _, _, err := s.idempotency.Begin(subject, "key-1", "fingerprint"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("expected ErrIdempotencyKeyInProgress, got %v", err)
+	}
+
+	if err := s.idempotency.Complete(record, 200, `{"message":"ok"}`); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	replayed, replay, err := s.idempotency.Begin(subject, "key-1", "fingerprint")
+	if err != nil || !replay {
+		t.Fatalf("expected a replay, got replay %v and error %v", replay, err)
+	}
+	if replayed.ResponseStatus != 200 || replayed.ResponseBody != `{"message":"ok"}` {
+		t.Errorf("expected the stored response, got %d %s", replayed.ResponseStatus, replayed.ResponseBody)
+	}
+
+	if _, _, err := s.idempotency.Begin(subject, "key-1", "other fingerprint"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// keys belong to the account that sent them
+	if _, replay, err := s.idempotency.Begin(uuid.New(), "key-1", "fingerprint"); err != nil || replay {
+		t.Errorf("expected another account to get a fresh claim, got replay %v and error %v", replay, err)
+	}
+}
+
+func TestIdempotencyReleasedKeyCanBeRetried(t *testing.T) {
+	s := newTestServices(t)
+	subject := uuid.New()
+
+	record, _, err := s.idempotency.Begin(subject, "key-1", "fingerprint")
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := s.idempotency.Release(record); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	if _, replay, err := s.idempotency.Begin(subject, "key-1", "fingerprint"); err != nil || replay {
+		t.Errorf("expected a fresh claim after the release, got replay %v and error %v", replay, err)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// FakeProvider replays a fixed script of responses, one per user turn, so the chat flow can run offline.
+// Once the script runs out the last response is repeated.
+type FakeProvider struct {
+	script []schemas.LLMResponse
+}
+
+var defaultFakeScript = []schemas.LLMResponse{
+	{
+		NextAction: "CONTINUE_CHAT",
+		Reply:      "Halo! Silakan pilih bahasa yang Anda inginkan: a. Bahasa Indonesia b. English",
+	},
+	{
+		NextAction: "CONTINUE_CHAT",
+		Reply:      "Apa keluhan utama Anda? (demam, batuk, sakit kepala)",
+	},
+	{
+		NextAction:   "APPOINTMENT",
+		Reply:        "Berdasarkan gejala Anda, saya sarankan Anda menemui dokter. Nomor antrean Anda telah dikirim ke email Anda.",
+		PreDiagnosis: "Common cold",
+	},
+}
+
+const fakeStreamChunkSize = 16
+
+// doctorIDPattern matches the doctor IDs listed in the system prompt
+var doctorIDPattern = regexp.MustCompile(`- \[([0-9a-fA-F-]{36})\]`)
+
+// NewFakeProvider loads the script from a JSON file containing an array of LLM responses, or uses the default script if path is empty.
+func NewFakeProvider(path string) (*FakeProvider, error) {
+	if path == "" {
+		return &FakeProvider{script: defaultFakeScript}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake LLM script: %w", err)
+	}
+
+	var script []schemas.LLMResponse
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse fake LLM script: %w", err)
+	}
+
+	if len(script) == 0 {
+		return nil, fmt.Errorf("fake LLM script is empty")
+	}
+
+	return &FakeProvider{script: script}, nil
+}
+
+func (p *FakeProvider) GenerateResponse(ctx context.Context, request LLMRequest) (schemas.LLMResponse, error) {
+	// pick the scripted response by the number of user turns so far
+	turn := 0
+	for _, messageItem := range request.History {
+		if messageItem.Role == "user" {
+			turn++
+		}
+	}
+	response := p.script[min(turn, len(p.script)-1)]
+
+	// appointments without a scripted doctor go to the first doctor listed in the prompt
+	if response.NextAction == "APPOINTMENT" && response.DoctorID == "" {
+		if match := doctorIDPattern.FindStringSubmatch(request.SystemPrompt); match != nil {
+			response.DoctorID = match[1]
+		}
+	}
+
+	return response, nil
+}
+
+func (p *FakeProvider) StreamResponse(ctx context.Context, request LLMRequest, onReply func(delta string)) (schemas.LLMResponse, error) {
+	response, err := p.GenerateResponse(ctx, request)
+	if err != nil {
+		return schemas.LLMResponse{}, err
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return schemas.LLMResponse{}, fmt.Errorf("failed to marshal fake response: %w", err)
+	}
+
+	// feed the raw JSON in small chunks like a real streaming model would
+	extractor := newReplyStreamExtractor(onReply)
+	for start := 0; start < len(raw); start += fakeStreamChunkSize {
+		extractor.Write(string(raw[start:min(start+fakeStreamChunkSize, len(raw))]))
+	}
+
+	return ParseJSON(extractor.Raw())
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"google.golang.org/genai"
+)
+
+type GeminiProvider struct {
+	client *genai.Client
+	model  string
+}
+
+func NewGeminiProvider(ctx context.Context, apiKey string, model string) (*GeminiProvider, error) {
+	// initialize the Gemini client once, it is safe for concurrent use
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating Gemini client: %w", err)
+	}
+
+	return &GeminiProvider{client: client, model: model}, nil
+}
+
+func convertMessageToGenaiContent(message schemas.Message) *genai.Content {
+	// Determine the role of the message
+	var role genai.Role
+	if message.Role == "user" {
+		role = genai.RoleUser
+	} else if message.Role == "omsapa" {
+		role = genai.RoleModel
+	} else {
+		return nil // Invalid role, return nil or handle error as needed
+	}
+
+	// Create a new genai.Content object from the message content and role
+	content := genai.NewContentFromText(message.Content, role)
+	return content
+}
+
+func (p *GeminiProvider) createChat(ctx context.Context, request LLMRequest) (*genai.Chat, error) {
+	var temperature float32 = 0.8
+	var TopP float32 = 0.95
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(request.SystemPrompt, genai.RoleUser),
+		ResponseMIMEType:  "application/json",
+		TopP:              &TopP,
+		Temperature:       &temperature,
+		MaxOutputTokens:   8192,
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"next_action":  {Type: genai.TypeString, Enum: []string{"CONTINUE_CHAT", "APPOINTMENT"}},
+				"reply":        {Type: genai.TypeString},
+				"doctor_id":    {Type: genai.TypeString},
+				"specialty":    {Type: genai.TypeString},
+				"prediagnosis": {Type: genai.TypeString},
+				"severity":     {Type: genai.TypeString, Enum: []string{"low", "medium", "high", "emergency"}},
+			},
+			Required: []string{"next_action", "reply", "doctor_id", "specialty", "prediagnosis", "severity"},
+		},
+	}
+
+	// build the genai history from the stored messages
+	var genaiHistory []*genai.Content
+	for _, messageItem := range request.History {
+		content := convertMessageToGenaiContent(messageItem)
+		if content != nil {
+			genaiHistory = append(genaiHistory, content)
+		}
+	}
+
+	chat, err := p.client.Chats.Create(ctx, p.model, config, genaiHistory)
+	if err != nil {
+		log.Printf("Error creating chat: %v\n", err)
+		return nil, fmt.Errorf("error creating chat: %w", err)
+	}
+
+	return chat, nil
+}
+
+func (p *GeminiProvider) GenerateResponse(ctx context.Context, request LLMRequest) (schemas.LLMResponse, error) {
+	chat, err := p.createChat(ctx, request)
+	if err != nil {
+		return schemas.LLMResponse{}, err
+	}
+
+	res, err := chat.SendMessage(ctx, genai.Part{Text: request.NewMessage})
+	if err != nil {
+		log.Printf("Error sending message: %v\n", err)
+		return schemas.LLMResponse{}, fmt.Errorf("error sending message: %w", err)
+	}
+
+	// get the response from the LLM
+	if res != nil && len(res.Candidates) > 0 && res.Candidates[0].Content != nil &&
+		len(res.Candidates[0].Content.Parts) > 0 {
+		return ParseJSON(res.Candidates[0].Content.Parts[0].Text)
+	}
+
+	return schemas.LLMResponse{}, fmt.Errorf("no response from LLM")
+}
+
+func (p *GeminiProvider) StreamResponse(ctx context.Context, request LLMRequest, onReply func(delta string)) (schemas.LLMResponse, error) {
+	chat, err := p.createChat(ctx, request)
+	if err != nil {
+		return schemas.LLMResponse{}, err
+	}
+
+	extractor := newReplyStreamExtractor(onReply)
+	for res, err := range chat.SendMessageStream(ctx, genai.Part{Text: request.NewMessage}) {
+		if err != nil {
+			log.Printf("Error streaming message: %v\n", err)
+			return schemas.LLMResponse{}, fmt.Errorf("error streaming message: %w", err)
+		}
+		extractor.Write(res.Text())
+	}
+
+	if extractor.Raw() == "" {
+		return schemas.LLMResponse{}, fmt.Errorf("no response from LLM")
+	}
+
+	return ParseJSON(extractor.Raw())
+}
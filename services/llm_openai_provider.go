@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// OpenAIProvider talks to any server implementing the OpenAI chat completions API (OpenAI, vLLM, Ollama, llama.cpp...).
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []openAIChatMessage `json:"messages"`
+	Temperature    float32             `json:"temperature"`
+	TopP           float32             `json:"top_p"`
+	MaxTokens      int                 `json:"max_tokens"`
+	ResponseFormat map[string]string   `json:"response_format"`
+	Stream         bool                `json:"stream,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type openAIChatStreamChunk struct {
+	Choices []struct {
+		Delta openAIChatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+func NewOpenAIProvider(baseURL string, apiKey string, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: config.GetEnvDuration("LLM_TIMEOUT", 60*time.Second)},
+	}
+}
+
+func buildOpenAIMessages(request LLMRequest) []openAIChatMessage {
+	messages := []openAIChatMessage{{Role: "system", Content: request.SystemPrompt}}
+
+	for _, messageItem := range request.History {
+		switch messageItem.Role {
+		case "user":
+			messages = append(messages, openAIChatMessage{Role: "user", Content: messageItem.Content})
+		case "omsapa":
+			messages = append(messages, openAIChatMessage{Role: "assistant", Content: messageItem.Content})
+		}
+	}
+
+	return append(messages, openAIChatMessage{Role: "user", Content: request.NewMessage})
+}
+
+// send posts the chat completion request and returns the response, the caller must close its body.
+func (p *OpenAIProvider) send(ctx context.Context, request LLMRequest, stream bool) (*http.Response, error) {
+	body := openAIChatRequest{
+		Model:          p.model,
+		Messages:       buildOpenAIMessages(request),
+		Temperature:    0.8,
+		TopP:           0.95,
+		MaxTokens:      8192,
+		ResponseFormat: map[string]string{"type": "json_object"},
+		Stream:         stream,
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal LLM request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error from LLM server: %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (p *OpenAIProvider) GenerateResponse(ctx context.Context, request LLMRequest) (schemas.LLMResponse, error) {
+	resp, err := p.send(ctx, request, false)
+	if err != nil {
+		return schemas.LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return schemas.LLMResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(result.Choices) == 0 {
+		return schemas.LLMResponse{}, fmt.Errorf("no response from LLM")
+	}
+
+	return ParseJSON(result.Choices[0].Message.Content)
+}
+
+func (p *OpenAIProvider) StreamResponse(ctx context.Context, request LLMRequest, onReply func(delta string)) (schemas.LLMResponse, error) {
+	resp, err := p.send(ctx, request, true)
+	if err != nil {
+		return schemas.LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	extractor := newReplyStreamExtractor(onReply)
+
+	// the response is a stream of server-sent events, each carrying a JSON chunk
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, found := strings.CutPrefix(scanner.Text(), "data:")
+		if !found {
+			continue
+		}
+
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return schemas.LLMResponse{}, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+
+		if len(chunk.Choices) > 0 {
+			extractor.Write(chunk.Choices[0].Delta.Content)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return schemas.LLMResponse{}, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	if extractor.Raw() == "" {
+		return schemas.LLMResponse{}, fmt.Errorf("no response from LLM")
+	}
+
+	return ParseJSON(extractor.Raw())
+}
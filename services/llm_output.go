@@ -0,0 +1,93 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// LLMOutputError is returned when the LLM answers with something that is not a usable response, even after
+// being asked to repair it. Sending the message again usually works.
+type LLMOutputError struct {
+	Reason string // what is wrong with the output, also sent to the LLM in the repair prompt
+	Raw    string // the raw output, empty if the provider already decoded it
+}
+
+func (e *LLMOutputError) Error() string {
+	return "unusable LLM output: " + e.Reason
+}
+
+// llmRepairAttempts is how many times the LLM is asked to fix an unusable response before giving up
+func llmRepairAttempts() int {
+	return config.GetEnvInt("LLM_REPAIR_ATTEMPTS", 2)
+}
+
+// ParseJSON extracts the JSON response from the raw LLM output, which may be wrapped in markdown code fences
+// or surrounded by other text. It returns an LLMOutputError if no JSON object can be decoded.
+func ParseJSON(input string) (schemas.LLMResponse, error) {
+	raw := strings.TrimSpace(input)
+
+	// drop the ```json ... ``` fences some models add
+	if fenced, found := strings.CutPrefix(raw, "```"); found {
+		fenced = strings.TrimPrefix(fenced, "json")
+		fenced, _ = strings.CutSuffix(strings.TrimSpace(fenced), "```")
+		raw = strings.TrimSpace(fenced)
+	}
+
+	// keep only the outermost object if there is text around it
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start == -1 || end < start {
+		return schemas.LLMResponse{}, &LLMOutputError{Reason: "the output is not a JSON object", Raw: input}
+	}
+
+	var responseJSON schemas.LLMResponse
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &responseJSON); err != nil {
+		return schemas.LLMResponse{}, &LLMOutputError{Reason: fmt.Sprintf("the output is not valid JSON (%v)", err), Raw: input}
+	}
+
+	responseJSON.NextAction = strings.ToUpper(strings.TrimSpace(responseJSON.NextAction))
+	return responseJSON, nil
+}
+
+// validateLLMResponse checks the response has the fields its next action needs
+func validateLLMResponse(response schemas.LLMResponse) error {
+	// an emergency is handled the same way whatever else the response says
+	if isEmergencyResponse(response) {
+		return nil
+	}
+
+	var missing []string
+	if strings.TrimSpace(response.Reply) == "" {
+		missing = append(missing, "reply")
+	}
+
+	switch response.NextAction {
+	case "CONTINUE_CHAT":
+	case "APPOINTMENT":
+		if strings.TrimSpace(response.DoctorID) == "" && strings.TrimSpace(response.Specialty) == "" {
+			missing = append(missing, "doctor_id")
+		}
+		if strings.TrimSpace(response.PreDiagnosis) == "" {
+			missing = append(missing, "prediagnosis")
+		}
+	default:
+		return &LLMOutputError{Reason: fmt.Sprintf("next_action %q is not CONTINUE_CHAT or APPOINTMENT", response.NextAction)}
+	}
+
+	if len(missing) > 0 {
+		return &LLMOutputError{Reason: fmt.Sprintf("next_action %s requires %s", response.NextAction, strings.Join(missing, " and "))}
+	}
+	return nil
+}
+
+// repairRequest asks the LLM to answer again, telling it what was wrong with its last output
+func repairRequest(request LLMRequest, outputErr *LLMOutputError) LLMRequest {
+	request.SystemPrompt += fmt.Sprintf(
+		"\n\nREPAIR: your previous answer could not be used because %s. Answer again with only the JSON object in the format described above.",
+		outputErr.Reason,
+	)
+	return request
+}
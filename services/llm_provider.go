@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// LLMRequest holds everything a provider needs to generate the next reply of a session.
+type LLMRequest struct {
+	SystemPrompt string
+	History      []schemas.Message // previous messages, role is either "user" or "omsapa"
+	NewMessage   string
+}
+
+// LLMProvider generates the structured reply for a chat session.
+type LLMProvider interface {
+	GenerateResponse(ctx context.Context, request LLMRequest) (schemas.LLMResponse, error)
+
+	// StreamResponse works like GenerateResponse but calls onReply with each new piece of the reply text as it is generated.
+	StreamResponse(ctx context.Context, request LLMRequest, onReply func(delta string)) (schemas.LLMResponse, error)
+}
+
+// NewLLMProviderFromEnv creates the provider selected by LLM_PROVIDER (gemini, openai or fake).
+func NewLLMProviderFromEnv() (LLMProvider, error) {
+	var provider LLMProvider
+	var err error
+
+	switch name := config.GetEnv("LLM_PROVIDER", "gemini"); name {
+	case "gemini":
+		provider, err = NewGeminiProvider(context.Background(), os.Getenv("GEMINI_API_KEY"), os.Getenv("GEMINI_MODEL"))
+	case "openai":
+		provider = NewOpenAIProvider(
+			config.GetEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			os.Getenv("OPENAI_API_KEY"),
+			os.Getenv("OPENAI_MODEL"),
+		)
+	case "fake":
+		provider, err = NewFakeProvider(os.Getenv("LLM_FAKE_SCRIPT"))
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", name)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating LLM provider: %w", err)
+	}
+
+	log.Printf("Using LLM provider: %T\n", provider)
+	return provider, nil
+}
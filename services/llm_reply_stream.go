@@ -0,0 +1,135 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// replyKeyPattern matches the start of the reply string value in the raw JSON response
+var replyKeyPattern = regexp.MustCompile(`"reply"\s*:\s*"`)
+
+// replyStreamExtractor receives the raw JSON response of the LLM chunk by chunk and
+// emits the decoded text of its "reply" field as soon as it arrives.
+type replyStreamExtractor struct {
+	raw      strings.Builder
+	pos      int  // position in raw up to which the reply value has been decoded
+	inReply  bool // the opening quote of the reply value has been found
+	done     bool // the closing quote of the reply value has been found
+	onReply  func(delta string)
+	searchAt int // position in raw from which to look for the reply key
+}
+
+func newReplyStreamExtractor(onReply func(delta string)) *replyStreamExtractor {
+	return &replyStreamExtractor{onReply: onReply}
+}
+
+// Write appends a raw chunk and emits any newly decoded reply text.
+func (e *replyStreamExtractor) Write(chunk string) {
+	e.raw.WriteString(chunk)
+	if e.done {
+		return
+	}
+
+	raw := e.raw.String()
+
+	if !e.inReply {
+		loc := replyKeyPattern.FindStringIndex(raw[e.searchAt:])
+		if loc == nil {
+			// keep a small tail in case the key is split across chunks
+			e.searchAt = max(0, len(raw)-16)
+			return
+		}
+		e.inReply = true
+		e.pos = e.searchAt + loc[1]
+	}
+
+	var delta strings.Builder
+	i := e.pos
+	for i < len(raw) {
+		ch := raw[i]
+
+		if ch == '"' {
+			e.done = true
+			i++
+			break
+		}
+
+		if ch == '\\' {
+			decoded, size, ok := decodeJSONEscape(raw[i:])
+			if !ok {
+				break // escape sequence not complete yet
+			}
+			delta.WriteString(decoded)
+			i += size
+			continue
+		}
+
+		// only emit complete UTF-8 characters
+		if !utf8.FullRuneInString(raw[i:]) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(raw[i:])
+		delta.WriteString(raw[i : i+size])
+		i += size
+	}
+	e.pos = i
+
+	if delta.Len() > 0 && e.onReply != nil {
+		e.onReply(delta.String())
+	}
+}
+
+// Raw returns the full raw response received so far.
+func (e *replyStreamExtractor) Raw() string {
+	return e.raw.String()
+}
+
+// decodeJSONEscape decodes the escape sequence at the start of s, returning false if it is not complete yet.
+func decodeJSONEscape(s string) (string, int, bool) {
+	if len(s) < 2 {
+		return "", 0, false
+	}
+
+	switch s[1] {
+	case '"', '\\', '/':
+		return string(s[1]), 2, true
+	case 'b':
+		return "\b", 2, true
+	case 'f':
+		return "\f", 2, true
+	case 'n':
+		return "\n", 2, true
+	case 'r':
+		return "\r", 2, true
+	case 't':
+		return "\t", 2, true
+	case 'u':
+		if len(s) < 6 {
+			return "", 0, false
+		}
+		r1, err := strconv.ParseUint(s[2:6], 16, 32)
+		if err != nil {
+			return "", 6, true // malformed escape, skip it
+		}
+
+		// surrogate pairs need the second half before they can be decoded
+		if utf16.IsSurrogate(rune(r1)) {
+			if len(s) < 12 {
+				return "", 0, false
+			}
+			if s[6] == '\\' && s[7] == 'u' {
+				if r2, err := strconv.ParseUint(s[8:12], 16, 32); err == nil {
+					return string(utf16.DecodeRune(rune(r1), rune(r2))), 12, true
+				}
+			}
+			return string(utf8.RuneError), 6, true
+		}
+
+		return string(rune(r1)), 6, true
+	default:
+		return "", 2, true // unknown escape, skip it
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/emails"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// notification templates, each has an email (html and plain text) and a short text variant per language
+const (
+	templateOTP        = "otp"
+	templateQueue      = "queue"
+	templateQueueNear  = "queue_near"
+	templateStaffAlert = "staff_alert"
+)
+
+var notificationSubjects = map[string]map[string]string{
+	templateOTP: {
+		models.LanguageEnglish:    "Your OTP Code",
+		models.LanguageIndonesian: "Kode OTP Anda",
+	},
+	templateQueue: {
+		models.LanguageEnglish:    "Queue Notification",
+		models.LanguageIndonesian: "Notifikasi Antrean",
+	},
+	templateQueueNear: {
+		models.LanguageEnglish:    "Your Turn Is Near",
+		models.LanguageIndonesian: "Giliran Anda Sudah Dekat",
+	},
+	templateStaffAlert: {
+		models.LanguageEnglish:    "Emergency Patient",
+		models.LanguageIndonesian: "Pasien Gawat Darurat",
+	},
+}
+
+// the templates are embedded in the binary, so a parse error is a build problem and may panic at startup
+var (
+	htmlTemplates = map[string]*htmltemplate.Template{
+		models.LanguageEnglish:    htmltemplate.Must(htmltemplate.ParseFS(emails.Templates, "en/*.html")),
+		models.LanguageIndonesian: htmltemplate.Must(htmltemplate.ParseFS(emails.Templates, "id/*.html")),
+	}
+	textTemplates = map[string]*texttemplate.Template{
+		models.LanguageEnglish:    texttemplate.Must(texttemplate.ParseFS(emails.Templates, "en/*.txt")),
+		models.LanguageIndonesian: texttemplate.Must(texttemplate.ParseFS(emails.Templates, "id/*.txt")),
+	}
+)
+
+// renderedNotification is a rendered template ready to be put in a notification
+type renderedNotification struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// otpTemplateData fills the otp template
+type otpTemplateData struct {
+	Code             string
+	ExpiresInMinutes int
+}
+
+// queueTemplateData fills the queue template
+type queueTemplateData struct {
+	QueueNumber        int
+	CurrentQueueNumber int
+	EstimatedWait      string
+	DoctorName         string
+	DoctorSpecialty    string
+	RoomNumber         string
+	ServiceDate        string // set when the ticket is for a later day
+}
+
+// queueNearTemplateData fills the queue_near template
+type queueNearTemplateData struct {
+	QueueNumber     int
+	PositionsAhead  int
+	EstimatedWait   string
+	DoctorName      string
+	DoctorSpecialty string
+	RoomNumber      string
+}
+
+// staffAlertTemplateData fills the staff_alert template
+type staffAlertTemplateData struct {
+	Reason      string
+	PatientName string
+	PatientAge  string
+	Heartrate   string
+	Bodytemp    string
+	Message     string
+	QueueNumber int // 0 if no ticket could be handed out
+	DoctorName  string
+	RoomNumber  string
+}
+
+// defaultLanguage is used for users without a supported language preference
+func defaultLanguage() string {
+	return normalizeLanguage(config.GetEnv("DEFAULT_LANGUAGE", models.LanguageEnglish), models.LanguageEnglish)
+}
+
+func normalizeLanguage(language string, fallback string) string {
+	switch language {
+	case models.LanguageEnglish, models.LanguageIndonesian:
+		return language
+	default:
+		return fallback
+	}
+}
+
+// renderNotification renders the template in the given language, as an email with an html and a plain text
+// body, or as a short text message for the SMS and WhatsApp channels
+func renderNotification(name string, channel string, language string, data any) (renderedNotification, error) {
+	language = normalizeLanguage(language, defaultLanguage())
+	rendered := renderedNotification{Subject: notificationSubjects[name][language]}
+
+	if channel != schemas.NotificationChannelEmail {
+		var text bytes.Buffer
+		if err := textTemplates[language].ExecuteTemplate(&text, name+"_short.txt", data); err != nil {
+			return renderedNotification{}, fmt.Errorf("failed to render %s/%s_short.txt: %w", language, name, err)
+		}
+		rendered.Text = strings.TrimSpace(text.String())
+		return rendered, nil
+	}
+
+	var html bytes.Buffer
+	if err := htmlTemplates[language].ExecuteTemplate(&html, name+"_mail.html", data); err != nil {
+		return renderedNotification{}, fmt.Errorf("failed to render %s/%s_mail.html: %w", language, name, err)
+	}
+
+	var text bytes.Buffer
+	if err := textTemplates[language].ExecuteTemplate(&text, name+"_mail.txt", data); err != nil {
+		return renderedNotification{}, fmt.Errorf("failed to render %s/%s_mail.txt: %w", language, name, err)
+	}
+
+	rendered.Text = text.String()
+	rendered.HTML = html.String()
+	return rendered, nil
+}
+
+// userContact returns the channel and address to reach the user on, email unless they prefer
+// a phone channel and have verified their phone number
+func userContact(user *models.User) (string, string) {
+	switch user.PreferredChannel {
+	case schemas.NotificationChannelSMS, schemas.NotificationChannelWhatsApp:
+		if user.Phone != nil && *user.Phone != "" && user.PhoneVerifiedAt != nil {
+			return user.PreferredChannel, *user.Phone
+		}
+	}
+	return schemas.NotificationChannelEmail, user.Email
+}
+
+// newUserNotification renders the template for the user's preferred channel and language
+func newUserNotification(user *models.User, kind string, name string, data any) (schemas.Notification, error) {
+	channel, to := userContact(user)
+	return newNotification(channel, to, user.Language, kind, name, data)
+}
+
+// newNotification renders the template for the given channel, address and language
+func newNotification(channel string, to string, language string, kind string, name string, data any) (schemas.Notification, error) {
+	rendered, err := renderNotification(name, channel, language, data)
+	if err != nil {
+		return schemas.Notification{}, err
+	}
+
+	return schemas.Notification{
+		Kind:    kind,
+		Channel: channel,
+		To:      to,
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// Notifier delivers notifications to patients.
+type Notifier interface {
+	Send(ctx context.Context, notification schemas.Notification) error
+}
+
+// ChannelNotifier routes every notification to the notifier configured for its channel.
+type ChannelNotifier struct {
+	notifiers map[string]Notifier
+}
+
+func NewChannelNotifier(notifiers map[string]Notifier) *ChannelNotifier {
+	return &ChannelNotifier{notifiers: notifiers}
+}
+
+func (n *ChannelNotifier) Send(ctx context.Context, notification schemas.Notification) error {
+	channel := notification.Channel
+	if channel == "" {
+		channel = schemas.NotificationChannelEmail
+	}
+
+	channelNotifier, ok := n.notifiers[channel]
+	if !ok {
+		return fmt.Errorf("no notifier configured for channel %q", channel)
+	}
+	return channelNotifier.Send(ctx, notification)
+}
+
+// Supports reports whether a notifier is configured for the channel.
+func (n *ChannelNotifier) Supports(channel string) bool {
+	_, ok := n.notifiers[channel]
+	return ok
+}
+
+// NewNotifierFromEnv creates the email notifier selected by NOTIFIER (http, smtp, file or stdout) and
+// the SMS and WhatsApp gateway notifiers, if configured.
+func NewNotifierFromEnv() (*ChannelNotifier, error) {
+	from := config.GetEnv("EMAIL_FROM", "omsehat@sportsnow.app")
+
+	var n Notifier
+	name := config.GetEnv("NOTIFIER", "http")
+	switch name {
+	case "http":
+		url := config.GetEnv("EMAIL_SERVICE_URL", "http://52.230.88.220:16250/send-email")
+		n = NewHTTPMailNotifier(url, from, map[string]string{
+			schemas.NotificationKindOTP:   os.Getenv("EMAIL_OTP_TOKEN"),
+			schemas.NotificationKindQueue: os.Getenv("EMAIL_TOKEN"),
+		})
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is not configured")
+		}
+		n = NewSMTPNotifier(host, config.GetEnv("SMTP_PORT", "587"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case "file":
+		dir := config.GetEnv("NOTIFIER_OUTBOX_DIR", "outbox")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create notifier outbox directory: %w", err)
+		}
+		n = NewFileNotifier(dir, from)
+	case "stdout":
+		n = NewFileNotifier("", from)
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", name)
+	}
+
+	notifiers := map[string]Notifier{schemas.NotificationChannelEmail: n}
+	gateways := map[string]string{
+		schemas.NotificationChannelSMS:      "SMS_GATEWAY",
+		schemas.NotificationChannelWhatsApp: "WHATSAPP_GATEWAY",
+	}
+	for channel, prefix := range gateways {
+		if url := os.Getenv(prefix + "_URL"); url != "" {
+			notifiers[channel] = NewHTTPGatewayNotifier(channel, url, os.Getenv(prefix+"_TOKEN"))
+		} else if name == "file" || name == "stdout" {
+			// during development text messages end up next to the emails
+			notifiers[channel] = n
+		}
+	}
+
+	for _, channel := range []string{schemas.NotificationChannelEmail, schemas.NotificationChannelSMS, schemas.NotificationChannelWhatsApp} {
+		if channelNotifier, ok := notifiers[channel]; ok {
+			log.Printf("Using %s notifier: %T\n", channel, channelNotifier)
+		}
+	}
+	return NewChannelNotifier(notifiers), nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// FileNotifier writes every notification as an .eml file into a directory, or to stdout if no directory is set.
+// It is meant for local development, the files can be opened with any mail client.
+type FileNotifier struct {
+	dir  string
+	from string
+}
+
+// unsafeFileChars matches characters that should not end up in file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+
+func NewFileNotifier(dir string, from string) *FileNotifier {
+	return &FileNotifier{dir: dir, from: from}
+}
+
+func (n *FileNotifier) Send(ctx context.Context, notification schemas.Notification) error {
+	message, err := buildMIMEMessage(n.from, notification)
+	if err != nil {
+		return err
+	}
+
+	if n.dir == "" {
+		fmt.Printf("----- %s notification -----\n%s\n---------------------------\n", notification.Kind, message)
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.eml",
+		time.Now().Format("20060102-150405.000000"),
+		notification.Kind,
+		unsafeFileChars.ReplaceAllString(notification.To, "_"),
+	)
+	if err := os.WriteFile(filepath.Join(n.dir, name), message, 0o644); err != nil {
+		return fmt.Errorf("failed to write notification file: %w", err)
+	}
+
+	return nil
+}
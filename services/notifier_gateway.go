@@ -0,0 +1,69 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// HTTPGatewayNotifier sends text messages through an HTTP gateway, used for the SMS and WhatsApp channels.
+// The gateway receives a JSON body with the channel, phone number and message.
+type HTTPGatewayNotifier struct {
+	channel string
+	url     string
+	token   string
+	client  *http.Client
+}
+
+type gatewayMessage struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+func NewHTTPGatewayNotifier(channel string, url string, token string) *HTTPGatewayNotifier {
+	return &HTTPGatewayNotifier{
+		channel: channel,
+		url:     url,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *HTTPGatewayNotifier) Send(ctx context.Context, notification schemas.Notification) error {
+	jsonData, err := json.Marshal(gatewayMessage{
+		Channel: n.channel,
+		To:      notification.To,
+		Message: notification.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s gateway request: %w", n.channel, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("error from %s gateway: %s", n.channel, resp.Status)
+	}
+
+	return nil
+}
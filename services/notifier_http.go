@@ -0,0 +1,64 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+)
+
+// HTTPMailNotifier sends emails through the OmSEHAT mail service.
+type HTTPMailNotifier struct {
+	url    string
+	from   string
+	tokens map[string]string // bearer token per notification kind
+	client *http.Client
+}
+
+func NewHTTPMailNotifier(url string, from string, tokens map[string]string) *HTTPMailNotifier {
+	return &HTTPMailNotifier{
+		url:    url,
+		from:   from,
+		tokens: tokens,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *HTTPMailNotifier) Send(ctx context.Context, notification schemas.Notification) error {
+	email := schemas.Email{
+		To:      notification.To,
+		Subject: notification.Subject,
+		Body:    notification.Text,
+		From:    n.from,
+		HTML:    notification.HTML,
+	}
+
+	jsonData, err := json.Marshal(email)
+	if err != nil {
+		return fmt.Errorf("failed to marshal email request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.tokens[notification.Kind])
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("error from email service: %s", resp.Status)
+	}
+
+	return nil
+}
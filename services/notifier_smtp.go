@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+)
+
+// SMTPNotifier sends emails directly to an SMTP server, such as a local MailHog.
+type SMTPNotifier struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPNotifier(host string, port string, username string, password string, from string) *SMTPNotifier {
+	return &SMTPNotifier{host: host, port: port, username: username, password: password, from: from}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, notification schemas.Notification) error {
+	message, err := buildMIMEMessage(n.from, notification)
+	if err != nil {
+		return err
+	}
+
+	// servers without authentication (like MailHog) are used without credentials
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	if err := smtp.SendMail(net.JoinHostPort(n.host, n.port), auth, n.from, []string{notification.To}, message); err != nil {
+		return fmt.Errorf("failed to send email over SMTP: %w", err)
+	}
+
+	return nil
+}
+
+// buildMIMEMessage renders the notification as a multipart/alternative email with a text and an HTML body
+func buildMIMEMessage(from string, notification schemas.Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := []struct{ key, value string }{
+		{"From", from},
+		{"To", notification.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", notification.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@omsehat>", uuid.NewString())},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+
+	var header bytes.Buffer
+	for _, h := range headers {
+		fmt.Fprintf(&header, "%s: %s\r\n", h.key, h.value)
+	}
+	header.WriteString("\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", notification.Text},
+		{"text/html; charset=utf-8", notification.HTML},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build email: %w", err)
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to build email: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build email: %w", err)
+	}
+
+	return append(header.Bytes(), buf.Bytes()...), nil
+}
@@ -1,125 +1,200 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
-	"net/http"
-	"os"
-	"strings"
+	"math/big"
 	"time"
 
 	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
 	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func generateOTP() string {
-	// Generate a random 6-digit OTP
-	otp := fmt.Sprintf("%06d", rand.Intn(1000000))
-	return otp
+var (
+	ErrOTPInvalid  = errors.New("invalid OTP")
+	ErrOTPExpired  = errors.New("OTP has expired, please request a new one")
+	ErrOTPLocked   = errors.New("too many failed OTP attempts, please try again later")
+	ErrOTPCooldown = errors.New("please wait before requesting a new OTP")
+)
+
+// OTPRetryError wraps an OTP error that can be retried after a known delay.
+type OTPRetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *OTPRetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OTPRetryError) Unwrap() error {
+	return e.Err
+}
+
+func otpTTL() time.Duration {
+	return config.GetEnvDuration("OTP_TTL", 5*time.Minute)
 }
 
-func ValidateOTP(input schemas.OTPInput) (*models.Session, error) {
-	// get the user from the input
-	var user models.User
-	err := config.DB.Where("email = ?", input.Email).First(&user).Error
+func otpMaxAttempts() int {
+	return config.GetEnvInt("OTP_MAX_ATTEMPTS", 5)
+}
+
+func otpLockoutDuration() time.Duration {
+	return config.GetEnvDuration("OTP_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+func otpResendCooldown() time.Duration {
+	return config.GetEnvDuration("OTP_RESEND_COOLDOWN", time.Minute)
+}
+
+func generateOTP() (string, error) {
+	// Generate a random 6-digit OTP using a CSPRNG
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return "", fmt.Errorf("failed to generate OTP: %w", err)
 	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
 
-	// check if OTP sent to the user is valid
-	if user.OTP != input.OTP {
-		return nil, fmt.Errorf("invalid OTP")
+// issueOTP generates a new OTP for the user and stores its hash, returning the plaintext code to be sent.
+// The user is not saved, the caller is responsible for persisting it.
+func issueOTP(user *models.User) (string, error) {
+	now := time.Now()
+
+	// a locked user cannot request a new code until the lockout expires
+	if user.OTPLockedUntil != nil && user.OTPLockedUntil.After(now) {
+		return "", &OTPRetryError{Err: ErrOTPLocked, RetryAfter: user.OTPLockedUntil.Sub(now)}
 	}
 
-	// create a new session for the user with the data from the input
-	newSession := models.Session{
-		UserID:    user.ID,
-		Weight:    input.Weight,
-		Height:    input.Height,
-		Heartrate: input.Heartrate,
-		Bodytemp:  input.Bodytemp,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	// enforce the resend cooldown
+	if user.OTPIssuedAt != nil {
+		if nextAllowed := user.OTPIssuedAt.Add(otpResendCooldown()); nextAllowed.After(now) {
+			return "", &OTPRetryError{Err: ErrOTPCooldown, RetryAfter: nextAllowed.Sub(now)}
+		}
 	}
 
-	// save the session to the database
-	err = config.DB.Create(&newSession).Error
+	otp, hash, err := newOTP()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		return "", err
 	}
 
-	// update user's OTP to nil after successful validation
-	user.OTP = ""
-	err = config.DB.Save(&user).Error
+	user.OTPHash = hash
+	user.OTPIssuedAt = &now
+	user.OTPLockedUntil = nil
+
+	return otp, nil
+}
+
+// newOTP generates a code and its bcrypt hash
+func newOTP() (string, string, error) {
+	otp, err := generateOTP()
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user OTP: %w", err)
+		return "", "", err
 	}
 
-	return &newSession, nil
+	hash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash OTP: %w", err)
+	}
+	return otp, string(hash), nil
 }
 
-func sendOTPEmail(to string, otp string, token string) (map[string]interface{}, error) {
+// registerFailedOTPAttempt increments the user's failed attempt counter and locks the user once the limit is reached.
+func (s *UserService) registerFailedOTPAttempt(user *models.User) error {
+	attempts, err := s.store.Users().IncrementFailedOTPAttempts(user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record OTP attempt: %w", err)
+	}
+
+	if attempts < otpMaxAttempts() {
+		return ErrOTPInvalid
+	}
 
-	email := schemas.Email{
-		To:      to,
-		Subject: "Your OTP Code",
-		Body:    "",
-		From:    "omsehat@sportsnow.app",
-		HTML:    injectOtpIntoHtml(otp),
+	// lock the user and invalidate the current code, a new one has to be requested after the lockout
+	lockout := otpLockoutDuration()
+	lockedUntil := time.Now().Add(lockout)
+	if err := s.store.Users().ResetOTP(user.ID, &lockedUntil); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
 	}
 
-	// URL of the email service
-	url := "http://52.230.88.220:16250/send-email"
+	return &OTPRetryError{Err: ErrOTPLocked, RetryAfter: lockout}
+}
 
-	jsonData, err := json.Marshal(email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal email request: %w", err)
+func (s *UserService) ValidateOTP(input schemas.OTPInput) (*models.Session, *schemas.AuthTokens, error) {
+	// get the user from the input, unknown emails get the same error as a wrong code
+	user, err := s.store.Users().FindByEmail(input.Email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrOTPInvalid
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	now := time.Now()
+
+	// check if the user is locked out
+	if user.OTPLockedUntil != nil && user.OTPLockedUntil.After(now) {
+		return nil, nil, &OTPRetryError{Err: ErrOTPLocked, RetryAfter: user.OTPLockedUntil.Sub(now)}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	// check if the OTP has been issued and is still fresh
+	if user.OTPHash == "" || user.OTPIssuedAt == nil || now.After(user.OTPIssuedAt.Add(otpTTL())) {
+		return nil, nil, ErrOTPExpired
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// check if OTP sent to the user is valid
+	if bcrypt.CompareHashAndPassword([]byte(user.OTPHash), []byte(input.OTP)) != nil {
+		return nil, nil, s.registerFailedOTPAttempt(user)
+	}
+
+	// create a new session for the user with the data from the input
+	newSession := models.Session{
+		UserID:    user.ID,
+		Weight:    input.Weight,
+		Height:    input.Height,
+		Heartrate: input.Heartrate,
+		Bodytemp:  input.Bodytemp,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// save the session to the database
+	err = s.store.Sessions().Create(&newSession)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("error from email service: %s", resp.Status)
+	// clear the user's OTP state after successful validation so the code cannot be reused
+	err = s.store.Users().ResetOTP(user.ID, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update user OTP: %w", err)
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	// issue the patient's access and refresh tokens
+	tokens, err := IssueTokens(user.ID, models.RolePatient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue tokens: %w", err)
 	}
 
-	return result, nil
+	return &newSession, tokens, nil
 }
 
-func injectOtpIntoHtml(otpCode string) string {
-	// Path to the HTML file
-	htmlFilePath := "emails/otp_mail.html"
-
-	// Read the HTML file
-	htmlBytes, err := os.ReadFile(htmlFilePath)
+// enqueueOTP stores the OTP message to the given channel and address in the outbox as part of the given transaction
+func (s *UserService) enqueueOTP(tx repositories.Store, user *models.User, otp string, channel string, to string) error {
+	notification, err := newNotification(channel, to, user.Language, schemas.NotificationKindOTP, templateOTP, otpTemplateData{
+		Code:             otp,
+		ExpiresInMinutes: int(otpTTL().Minutes()),
+	})
 	if err != nil {
-		log.Fatalf("Failed to read HTML file: %v", err)
+		return err
 	}
 
-	// Convert the file content to a string
-	htmlString := string(htmlBytes)
+	// the code is no use once it expired, so it is not retried after that
+	expiresAt := time.Now().Add(otpTTL())
+	notification.ExpiresAt = &expiresAt
 
-	// Replace the placeholder with the OTP code
-	return strings.ReplaceAll(htmlString, "{{otp_code}}", otpCode)
+	return s.outbox.Enqueue(tx, notification)
 }
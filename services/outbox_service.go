@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOutboxMessageNotFound     = errors.New("outbox message not found")
+	ErrOutboxMessageNotRetryable = errors.New("OTP messages are not resent, the user has to request a new code")
+	ErrOutboxMessageNotDead      = errors.New("only dead messages can be retried")
+)
+
+// OutboxService stores outgoing notifications and delivers them in the background.
+type OutboxService struct {
+	store    repositories.Store
+	notifier Notifier
+	// wake nudges the worker to deliver right away instead of waiting for the next poll
+	wake chan struct{}
+}
+
+func NewOutboxService(store repositories.Store, notifier Notifier) *OutboxService {
+	return &OutboxService{store: store, notifier: notifier, wake: make(chan struct{}, 1)}
+}
+
+func outboxMaxAttempts() int {
+	return config.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 8)
+}
+
+// outboxClaimTimeout is how long a batch claimed by a worker is skipped by the others. It has to cover sending the
+// whole batch, a message whose worker died is picked up again once it passes.
+func outboxClaimTimeout() time.Duration {
+	return config.GetEnvDuration("OUTBOX_CLAIM_TIMEOUT", 15*time.Minute)
+}
+
+// outboxBackoff returns how long to wait before the next attempt, doubling after every failed attempt
+func outboxBackoff(attempts int) time.Duration {
+	base := config.GetEnvDuration("OUTBOX_RETRY_BASE", 30*time.Second)
+	maxBackoff := config.GetEnvDuration("OUTBOX_RETRY_MAX", time.Hour)
+
+	backoff := time.Duration(float64(base) * math.Pow(2, float64(attempts-1)))
+	if backoff <= 0 || backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// ChannelAvailable reports whether notifications can be delivered over the channel.
+func (s *OutboxService) ChannelAvailable(channel string) bool {
+	if n, ok := s.notifier.(*ChannelNotifier); ok {
+		return n.Supports(channel)
+	}
+	return channel == schemas.NotificationChannelEmail
+}
+
+// Enqueue stores the notification in the outbox as part of the given transaction.
+// Call Wake once the transaction has been committed.
+func (s *OutboxService) Enqueue(tx repositories.Store, notification schemas.Notification) error {
+	now := time.Now()
+	message := models.OutboxMessage{
+		Kind:          notification.Kind,
+		Channel:       notification.Channel,
+		Recipient:     notification.To,
+		Subject:       notification.Subject,
+		Text:          notification.Text,
+		HTML:          notification.HTML,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+		ExpiresAt:     notification.ExpiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := tx.Outbox().Create(&message); err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return nil
+}
+
+// Wake makes the worker deliver pending messages right away.
+func (s *OutboxService) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWorker delivers pending outbox messages in the background until ctx is cancelled.
+func (s *OutboxService) StartWorker(ctx context.Context) {
+	interval := config.GetEnvDuration("OUTBOX_POLL_INTERVAL", 5*time.Second)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// keep going while full batches are being delivered
+			for s.processBatch(ctx) {
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// processBatch delivers a batch of due messages, returning true if the batch was full
+func (s *OutboxService) processBatch(ctx context.Context) bool {
+	batchSize := config.GetEnvInt("OUTBOX_BATCH_SIZE", 20)
+
+	messages, err := s.claimDue(batchSize)
+	if err != nil {
+		log.Printf("Error processing outbox: %v\n", err)
+		return false
+	}
+
+	for i := range messages {
+		s.deliver(ctx, &messages[i])
+		if err := s.store.Outbox().Save(&messages[i]); err != nil {
+			log.Printf("Error saving outbox message %s: %v\n", messages[i].ID, err)
+		}
+	}
+
+	return len(messages) == batchSize
+}
+
+// claimDue locks the due messages and counts the attempt, pushing their next attempt past the claim timeout so
+// several instances never deliver the same one. The transaction is committed before anything is sent, so the
+// rows are not held locked across the network calls.
+func (s *OutboxService) claimDue(limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		now := time.Now()
+
+		var err error
+		messages, err = tx.Outbox().LockDue(now, limit)
+		if err != nil {
+			return err
+		}
+
+		for i := range messages {
+			messages[i].Attempts++
+			messages[i].NextAttemptAt = now.Add(outboxClaimTimeout())
+			messages[i].UpdatedAt = now
+			if err := tx.Outbox().Save(&messages[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// deliver tries to send the claimed message and updates its status and next attempt
+func (s *OutboxService) deliver(ctx context.Context, message *models.OutboxMessage) {
+	now := time.Now()
+	message.UpdatedAt = now
+
+	if message.ExpiresAt != nil && now.After(*message.ExpiresAt) {
+		message.Status = models.OutboxStatusExpired
+		discardOTPBody(message)
+		return
+	}
+
+	err := errors.New("notifier is not configured")
+	if s.notifier != nil {
+		err = s.notifier.Send(ctx, schemas.Notification{
+			Kind:    message.Kind,
+			Channel: message.Channel,
+			To:      message.Recipient,
+			Subject: message.Subject,
+			Text:    message.Text,
+			HTML:    message.HTML,
+		})
+	}
+
+	if err == nil {
+		message.Status = models.OutboxStatusSent
+		message.SentAt = &now
+		message.LastError = ""
+		discardOTPBody(message)
+		return
+	}
+
+	message.LastError = err.Error()
+	if message.Attempts >= outboxMaxAttempts() {
+		message.Status = models.OutboxStatusDead
+		discardOTPBody(message)
+		log.Printf("Outbox message %s dead-lettered after %d attempts: %v\n", message.ID, message.Attempts, err)
+		return
+	}
+
+	message.NextAttemptAt = now.Add(outboxBackoff(message.Attempts))
+
+	// a code that would arrive after it expired is useless
+	if message.ExpiresAt != nil && message.NextAttemptAt.After(*message.ExpiresAt) {
+		message.Status = models.OutboxStatusExpired
+		discardOTPBody(message)
+		log.Printf("Outbox message %s expired after %d attempts: %v\n", message.ID, message.Attempts, err)
+		return
+	}
+
+	log.Printf("Outbox message %s failed (attempt %d), retrying at %s: %v\n", message.ID, message.Attempts, message.NextAttemptAt.Format(time.RFC3339), err)
+}
+
+// discardOTPBody drops the code of an OTP message that will not be sent again, it is only stored as a hash
+// on the user
+func discardOTPBody(message *models.OutboxMessage) {
+	if message.Kind == schemas.NotificationKindOTP {
+		message.Text = ""
+		message.HTML = ""
+	}
+}
+
+// GetMessages lists outbox messages, newest first, optionally filtered by status.
+func (s *OutboxService) GetMessages(status string, limit int) ([]models.OutboxMessage, error) {
+	messages, err := s.store.Outbox().List(status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// GetStats counts outbox messages per status.
+func (s *OutboxService) GetStats() (map[string]int64, error) {
+	counts, err := s.store.Outbox().CountByStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+
+	stats := map[string]int64{
+		models.OutboxStatusPending: 0,
+		models.OutboxStatusSent:    0,
+		models.OutboxStatusDead:    0,
+		models.OutboxStatusExpired: 0,
+	}
+	for status, count := range counts {
+		stats[status] = count
+	}
+	return stats, nil
+}
+
+// RetryMessage puts a dead message back in the queue for immediate delivery with a fresh attempt budget. Sent
+// messages would reach the recipient twice and expired ones are past their deadline, so only dead messages are
+// retried. OTP messages cannot be retried either, their code is discarded once the worker gives up on them.
+func (s *OutboxService) RetryMessage(messageID uuid.UUID) (*models.OutboxMessage, error) {
+	message, err := s.store.Outbox().FindByID(messageID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrOutboxMessageNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch outbox message: %w", err)
+	}
+
+	if message.Kind == schemas.NotificationKindOTP {
+		return nil, ErrOutboxMessageNotRetryable
+	}
+	if message.Status != models.OutboxStatusDead {
+		return nil, ErrOutboxMessageNotDead
+	}
+
+	message.Status = models.OutboxStatusPending
+	message.Attempts = 0
+	message.NextAttemptAt = time.Now()
+	message.UpdatedAt = time.Now()
+
+	if err := s.store.Outbox().Save(message); err != nil {
+		return nil, fmt.Errorf("failed to update outbox message: %w", err)
+	}
+
+	s.Wake()
+	return message, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// AverageConsultationDuration returns the doctor's mean time between calling a patient and completing the
+// consultation over their most recent consultations, or QUEUE_DEFAULT_CONSULTATION_DURATION without history.
+func (s *QueueService) AverageConsultationDuration(doctorID uuid.UUID) time.Duration {
+	fallback := config.GetEnvDuration("QUEUE_DEFAULT_CONSULTATION_DURATION", 10*time.Minute)
+
+	average, err := s.store.Queues().AverageConsultationDuration(doctorID, config.GetEnvInt("QUEUE_ETA_SAMPLE_SIZE", 20))
+	if err != nil || average <= 0 {
+		return fallback
+	}
+
+	return average
+}
+
+// estimateWaitMinutes converts the number of patients ahead into minutes
+func estimateWaitMinutes(ahead int, average time.Duration) *int {
+	minutes := int(math.Ceil(float64(ahead) * average.Minutes()))
+	return &minutes
+}
+
+// countActiveQueue returns how many patients the doctor is currently seeing (called or in consultation) on the date
+func countActiveQueue(store repositories.Store, doctorID uuid.UUID, serviceDate time.Time) int {
+	active, _ := store.Queues().Count(repositories.QueueFilter{
+		DoctorID:    doctorID,
+		ServiceDate: &serviceDate,
+		Statuses:    []string{models.QueueStatusCalled, models.QueueStatusInConsultation},
+	})
+	return active
+}
+
+// SetEstimatedWait fills in the queue entry's estimated wait from the tickets ahead of it.
+func (s *QueueService) SetEstimatedWait(queue *models.Queue) {
+	s.estimateWait(s.store, queue)
+}
+
+func (s *QueueService) estimateWait(store repositories.Store, queue *models.Queue) {
+	if queue == nil {
+		return
+	}
+
+	switch queue.Status {
+	case models.QueueStatusCalled, models.QueueStatusInConsultation:
+		queue.EstimatedWaitMinutes = estimateWaitMinutes(0, 0)
+		return
+	case models.QueueStatusWaiting:
+	default:
+		queue.EstimatedWaitMinutes = nil
+		return
+	}
+
+	// the wait of a ticket for a later day is not known yet
+	if bookedAhead(queue) {
+		queue.EstimatedWaitMinutes = nil
+		return
+	}
+
+	// everyone waiting with a lower number plus whoever is being seen right now, priority tickets are
+	// ahead of every regular ticket
+	serviceDate := utils.AsServiceDate(queue.ServiceDate)
+	waitingAhead, _ := store.Queues().Count(repositories.QueueFilter{
+		DoctorID:     queue.DoctorID,
+		ServiceDate:  &serviceDate,
+		Statuses:     []string{models.QueueStatusWaiting},
+		BeforeNumber: queue.Number,
+		Priority:     &queue.Priority,
+	})
+	if !queue.Priority {
+		priority := true
+		priorityWaiting, _ := store.Queues().Count(repositories.QueueFilter{
+			DoctorID:    queue.DoctorID,
+			ServiceDate: &serviceDate,
+			Statuses:    []string{models.QueueStatusWaiting},
+			Priority:    &priority,
+		})
+		waitingAhead += priorityWaiting
+	}
+
+	ahead := waitingAhead + countActiveQueue(store, queue.DoctorID, serviceDate)
+	queue.EstimatedWaitMinutes = estimateWaitMinutes(ahead, s.AverageConsultationDuration(queue.DoctorID))
+}
+
+// GetWaitingQueue returns the doctor's waiting tickets of today in order, each with its estimated wait.
+func (s *QueueService) GetWaitingQueue(doctorID uuid.UUID) ([]models.Queue, error) {
+	today := utils.ServiceDate(time.Now())
+
+	queues, err := s.store.Queues().Find(repositories.QueueFilter{
+		DoctorID:    doctorID,
+		ServiceDate: &today,
+		Statuses:    []string{models.QueueStatusWaiting},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	active := countActiveQueue(s.store, doctorID, today)
+	average := s.AverageConsultationDuration(doctorID)
+	for i := range queues {
+		queues[i].EstimatedWaitMinutes = estimateWaitMinutes(active+i, average)
+	}
+
+	return queues, nil
+}
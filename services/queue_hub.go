@@ -0,0 +1,116 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+)
+
+// Queue event types
+const (
+	QueueEventTicketCreated = "ticket_created"
+	QueueEventNowServing    = "now_serving"
+	QueueEventSkipped       = "skipped"
+	QueueEventCompleted     = "completed"
+	QueueEventCancelled     = "cancelled"
+	QueueEventEmergency     = "emergency" // an emergency patient was flagged, their ticket is served first
+)
+
+// QueueEventHub fans queue events out to board subscribers.
+// The in-process implementation only reaches subscribers of this instance, a Postgres
+// LISTEN/NOTIFY backed hub can implement the same interface to span several instances.
+type QueueEventHub interface {
+	Publish(event schemas.QueueEvent)
+
+	// Subscribe returns a channel receiving the events of one doctor, or of the whole clinic if doctorID is nil,
+	// and a function to cancel the subscription.
+	Subscribe(doctorID *uuid.UUID) (<-chan schemas.QueueEvent, func())
+}
+
+const (
+	clinicTopic          = "clinic"
+	subscriberBufferSize = 32
+)
+
+type InProcessQueueHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan schemas.QueueEvent]struct{}
+}
+
+func NewInProcessQueueHub() *InProcessQueueHub {
+	return &InProcessQueueHub{subscribers: map[string]map[chan schemas.QueueEvent]struct{}{}}
+}
+
+func doctorTopic(doctorID uuid.UUID) string {
+	return "doctor:" + doctorID.String()
+}
+
+func (h *InProcessQueueHub) Publish(event schemas.QueueEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// events without a doctor, such as an emergency patient without a ticket, only go to the clinic board
+	topics := []string{clinicTopic}
+	if event.DoctorID != uuid.Nil {
+		topics = append(topics, doctorTopic(event.DoctorID))
+	}
+
+	for _, topic := range topics {
+		for ch := range h.subscribers[topic] {
+			// never block the publisher on a slow subscriber
+			select {
+			case ch <- event:
+			default:
+				log.Printf("Dropping queue event for slow subscriber on %s\n", topic)
+			}
+		}
+	}
+}
+
+func (h *InProcessQueueHub) Subscribe(doctorID *uuid.UUID) (<-chan schemas.QueueEvent, func()) {
+	topic := clinicTopic
+	if doctorID != nil {
+		topic = doctorTopic(*doctorID)
+	}
+
+	ch := make(chan schemas.QueueEvent, subscriberBufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = map[chan schemas.QueueEvent]struct{}{}
+	}
+	h.subscribers[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[topic], ch)
+			if len(h.subscribers[topic]) == 0 {
+				delete(h.subscribers, topic)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// queueStatusEventType returns the board event type for a ticket that moved to status
+func queueStatusEventType(status string) string {
+	switch status {
+	case models.QueueStatusCalled, models.QueueStatusInConsultation:
+		return QueueEventNowServing
+	case models.QueueStatusSkipped:
+		return QueueEventSkipped
+	case models.QueueStatusDone:
+		return QueueEventCompleted
+	default:
+		return QueueEventCancelled
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// QueueReminderService sends "your turn is near" reminders to waiting patients.
+type QueueReminderService struct {
+	store  repositories.Store
+	queues *QueueService
+	outbox *OutboxService
+}
+
+func NewQueueReminderService(store repositories.Store, queues *QueueService, outbox *OutboxService) *QueueReminderService {
+	return &QueueReminderService{store: store, queues: queues, outbox: outbox}
+}
+
+// StartScheduler sends reminders in the background until ctx is cancelled.
+// Doctors are checked whenever their queue changes and on a regular interval for everyone waiting today.
+func (s *QueueReminderService) StartScheduler(ctx context.Context) {
+	interval := config.GetEnvDuration("REMINDER_CHECK_INTERVAL", 30*time.Second)
+	events, cancel := s.queues.Subscribe(nil)
+
+	go func() {
+		defer cancel()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				s.handleEvent(event)
+			case <-ticker.C:
+				s.checkReminders()
+			}
+		}
+	}()
+}
+
+// handleEvent checks the doctor whose queue changed. Events without a doctor, such as an emergency patient
+// without a ticket, leave every queue as it was.
+func (s *QueueReminderService) handleEvent(event schemas.QueueEvent) {
+	if event.DoctorID == uuid.Nil {
+		return
+	}
+	s.remindUpcomingPatients(event.DoctorID)
+}
+
+// checkReminders looks at every doctor that still has patients waiting for a reminder today
+func (s *QueueReminderService) checkReminders() {
+	doctorIDs, err := s.store.Queues().DoctorIDsWithPendingReminders(utils.ServiceDate(time.Now()))
+	if err != nil {
+		log.Printf("Error checking queue reminders: %v\n", err)
+		return
+	}
+
+	for _, doctorID := range doctorIDs {
+		s.remindUpcomingPatients(doctorID)
+	}
+}
+
+// remindUpcomingPatients queues a reminder for every waiting patient of the doctor who is at most
+// REMINDER_POSITIONS_AHEAD positions away or whose estimated wait dropped to REMINDER_ETA_MINUTES or less.
+// Set either threshold to 0 to disable it.
+func (s *QueueReminderService) remindUpcomingPatients(doctorID uuid.UUID) {
+	positionsThreshold := config.GetEnvInt("REMINDER_POSITIONS_AHEAD", 3)
+	etaThreshold := config.GetEnvInt("REMINDER_ETA_MINUTES", 10)
+
+	waiting, err := s.queues.GetWaitingQueue(doctorID)
+	if err != nil {
+		log.Printf("Error fetching waiting queue for reminders: %v\n", err)
+		return
+	}
+
+	active := countActiveQueue(s.store, doctorID, utils.ServiceDate(time.Now()))
+	reminded := false
+	for i := range waiting {
+		queue := &waiting[i]
+		if queue.ReminderSentAt != nil {
+			continue
+		}
+
+		ahead := active + i
+		nearByPosition := positionsThreshold > 0 && ahead <= positionsThreshold
+		nearByETA := etaThreshold > 0 && queue.EstimatedWaitMinutes != nil && *queue.EstimatedWaitMinutes <= etaThreshold
+		if !nearByPosition && !nearByETA {
+			continue
+		}
+
+		sent, err := s.sendReminder(queue, ahead)
+		if err != nil {
+			log.Printf("Error sending queue reminder for ticket %s: %v\n", queue.ID, err)
+			continue
+		}
+		reminded = reminded || sent
+	}
+
+	if reminded {
+		s.outbox.Wake()
+	}
+}
+
+// sendReminder marks the ticket as reminded and queues the reminder in one transaction.
+// It returns false if the ticket was already reminded or is no longer waiting.
+func (s *QueueReminderService) sendReminder(queue *models.Queue, ahead int) (bool, error) {
+	sent := false
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		// claim the reminder first, so concurrent checks never send it twice
+		now := time.Now()
+		claimed, err := tx.Queues().ClaimReminder(queue.ID, now)
+		if err != nil || !claimed {
+			return err
+		}
+
+		ticket, err := tx.Queues().FindWithDetails(queue.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.queues.enqueueQueueNearAlert(tx, &ticket.Session.User, ticket.Number, ahead, queue.EstimatedWaitMinutes, ticket.Doctor); err != nil {
+			return err
+		}
+
+		queue.ReminderSentAt = &now
+		sent = true
+		return nil
+	})
+
+	return sent, err
+}
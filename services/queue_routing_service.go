@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+var ErrDoctorFull = errors.New("doctor is fully booked")
+
+// bookedStatuses are the ticket statuses that take up a place in a doctor's capacity
+var bookedStatuses = []string{
+	models.QueueStatusWaiting,
+	models.QueueStatusCalled,
+	models.QueueStatusInConsultation,
+	models.QueueStatusDone,
+	models.QueueStatusSkipped,
+}
+
+// defaultDailyCapacity is the daily cap of doctors without one of their own, 0 is unlimited
+func defaultDailyCapacity() int {
+	return config.GetEnvInt("DOCTOR_DAILY_CAPACITY", 0)
+}
+
+// bookingHorizonDays is how many days ahead a patient can be booked when no doctor can take them today
+func bookingHorizonDays() int {
+	return config.GetEnvInt("QUEUE_BOOKING_HORIZON_DAYS", 7)
+}
+
+// assignment is the doctor and day a new ticket goes to, reason is set when they differ from the request
+type assignment struct {
+	doctor      models.Doctor
+	doctorID    uuid.UUID
+	serviceDate time.Time
+	window      shiftWindow // the shift the ticket is handed out in, see hasCapacity
+	requestedID uuid.UUID
+	reason      string
+
+	assignmentPath string // how the doctor was picked, see the QueueAssignment paths
+	triageReason   string // set for emergency tickets, which skip the capacity checks and are served first
+}
+
+// assignDoctor picks the doctor and day for a new ticket. The requested doctor gets it if they are on shift and
+// have room. A full doctor is replaced by the least busy doctor of the same specialty with room, a doctor off
+// shift by the least busy general practitioner on duty with room. Failing that the ticket is booked on the
+// requested doctor's next day with room. It returns ErrDoctorFull if the requested doctor is on shift but
+// nobody can take the patient, ErrDoctorUnavailable if they are off shift and nobody can.
+func (s *AvailabilityService) assignDoctor(store repositories.Store, doctorID uuid.UUID, at time.Time) (*assignment, error) {
+	today := utils.ServiceDate(at)
+	onShift, err := onShiftDoctors(store, at)
+	if err != nil {
+		return nil, err
+	}
+
+	var requested *onShiftDoctor
+	for i := range onShift {
+		if onShift[i].id == doctorID {
+			requested = &onShift[i]
+		}
+	}
+
+	var alternative *onShiftDoctor
+	var reason string
+	if requested != nil {
+		ok, err := hasCapacity(store, requested.id, requested.doctor, today, requested.window)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &assignment{doctor: requested.doctor, doctorID: requested.id, serviceDate: today, window: requested.window, requestedID: doctorID}, nil
+		}
+
+		alternative, err = leastBusyDoctor(store, onShift, today, func(doctor models.Doctor) bool {
+			return doctor.ID != requested.doctor.ID && strings.EqualFold(doctor.Specialty, requested.doctor.Specialty)
+		})
+		reason = models.QueueRoutingSameSpecialty
+	} else {
+		alternative, err = leastBusyDoctor(store, onShift, today, isGeneralPractitioner)
+		reason = models.QueueRoutingGeneralPractitioner
+	}
+	if err != nil {
+		return nil, err
+	}
+	if alternative != nil {
+		log.Printf("Doctor %s cannot take the patient, routing to %s (%s)\n", doctorID, alternative.id, reason)
+		return &assignment{doctor: alternative.doctor, doctorID: alternative.id, serviceDate: today, window: alternative.window, requestedID: doctorID, reason: reason}, nil
+	}
+
+	// nobody can take the patient today, book the requested doctor's next day with room
+	doctor, err := store.Doctors().FindByID(doctorID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("failed to fetch doctor: %w", err)
+	}
+	if err == nil && doctor.IsActive() {
+		date, window, found, err := nextAvailableDay(store, doctorID, *doctor, today)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			log.Printf("Doctor %s cannot take the patient today, booking them on %s\n", doctorID, date.Format(time.DateOnly))
+			return &assignment{doctor: *doctor, doctorID: doctorID, serviceDate: date, window: window, requestedID: doctorID, reason: models.QueueRoutingNextDay}, nil
+		}
+	}
+
+	if requested != nil {
+		return nil, ErrDoctorFull
+	}
+	return nil, ErrDoctorUnavailable
+}
+
+// leastBusyDoctor returns the doctor matching the predicate with room for another ticket today and the fewest
+// patients waiting, or nil if there is none
+func leastBusyDoctor(store repositories.Store, candidates []onShiftDoctor, today time.Time, match func(models.Doctor) bool) (*onShiftDoctor, error) {
+	var best *onShiftDoctor
+	bestWaiting := 0
+	for i := range candidates {
+		if !match(candidates[i].doctor) {
+			continue
+		}
+
+		ok, err := hasCapacity(store, candidates[i].id, candidates[i].doctor, today, candidates[i].window)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		waiting, err := countWaiting(store, candidates[i].id, today)
+		if err != nil {
+			return nil, err
+		}
+
+		if best == nil || waiting < bestWaiting {
+			best, bestWaiting = &candidates[i], waiting
+		}
+	}
+	return best, nil
+}
+
+// countWaiting returns how many patients are waiting for the doctor on the date
+func countWaiting(store repositories.Store, doctorID uuid.UUID, date time.Time) (int, error) {
+	waiting, err := store.Queues().Count(repositories.QueueFilter{
+		DoctorID:    doctorID,
+		ServiceDate: &date,
+		Statuses:    []string{models.QueueStatusWaiting},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count waiting patients: %w", err)
+	}
+	return waiting, nil
+}
+
+// nextAvailableDay returns the first day after today, within the booking horizon, on which the doctor works
+// and is not fully booked, with the window tickets booked for that day count against
+func nextAvailableDay(store repositories.Store, doctorID uuid.UUID, doctor models.Doctor, today time.Time) (time.Time, shiftWindow, bool, error) {
+	for days := 1; days <= bookingHorizonDays(); days++ {
+		date := today.AddDate(0, 0, days)
+
+		day, err := loadDaySchedule(store, doctorID, date)
+		if err != nil {
+			return time.Time{}, shiftWindow{}, false, err
+		}
+		if !day.worksOn(doctorID) {
+			continue
+		}
+
+		// the whole day, tickets booked ahead count against the total of the day's shift caps
+		window := shiftWindow{capacity: day.capacity(doctorID)}
+		ok, err := hasCapacity(store, doctorID, doctor, date, window)
+		if err != nil {
+			return time.Time{}, shiftWindow{}, false, err
+		}
+		if ok {
+			return date, window, true, nil
+		}
+	}
+	return time.Time{}, shiftWindow{}, false, nil
+}
+
+// hasCapacity reports whether the doctor can take another ticket on the date. window is the shift the ticket is
+// handed out in, its cap counts the tickets created during the shift plus every ticket booked ahead for the date,
+// as those are not tied to a shift. A window without hours covers the whole day and counts every ticket of the day.
+func hasCapacity(store repositories.Store, doctorID uuid.UUID, doctor models.Doctor, date time.Time, window shiftWindow) (bool, error) {
+	dailyCapacity := doctor.DailyCapacity
+	if dailyCapacity == 0 {
+		dailyCapacity = defaultDailyCapacity()
+	}
+
+	if dailyCapacity > 0 {
+		booked, err := store.Queues().Count(repositories.QueueFilter{
+			DoctorID:    doctorID,
+			ServiceDate: &date,
+			Statuses:    bookedStatuses,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to count booked tickets: %w", err)
+		}
+		if booked >= dailyCapacity {
+			return false, nil
+		}
+	}
+
+	if window.capacity > 0 {
+		filter := repositories.QueueFilter{
+			DoctorID:    doctorID,
+			ServiceDate: &date,
+			Statuses:    bookedStatuses,
+		}
+		if window.start != "" {
+			from, before := clockOn(date, window.start), clockOn(date, window.end)
+			filter.CreatedFrom, filter.CreatedBefore = &from, &before
+		}
+
+		booked, err := store.Queues().Count(filter)
+		if err != nil {
+			return false, fmt.Errorf("failed to count booked tickets: %w", err)
+		}
+
+		if window.start != "" {
+			bookedAhead, err := store.Queues().Count(repositories.QueueFilter{
+				DoctorID:      doctorID,
+				ServiceDate:   &date,
+				Statuses:      bookedStatuses,
+				CreatedBefore: &date,
+			})
+			if err != nil {
+				return false, fmt.Errorf("failed to count tickets booked ahead: %w", err)
+			}
+			booked += bookedAhead
+		}
+
+		if booked >= window.capacity {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// clockOn returns the HH:MM clock time on the date
+func clockOn(date time.Time, clock string) time.Time {
+	var hours, minutes int
+	fmt.Sscanf(clock, "%d:%d", &hours, &minutes)
+	return date.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+}
+
+func isGeneralPractitioner(doctor models.Doctor) bool {
+	return strings.EqualFold(strings.TrimSpace(doctor.Specialty), config.GeneralPractitionerSpecialty())
+}
+
+// DescribeRouting explains why the ticket went to another doctor or day than the LLM chose, nil if it did not.
+func DescribeRouting(queue *models.Queue) *schemas.QueueRouting {
+	if queue == nil || queue.RoutingReason == "" {
+		return nil
+	}
+
+	routing := &schemas.QueueRouting{
+		Reason:            queue.RoutingReason,
+		DoctorID:          &queue.DoctorID,
+		RequestedDoctorID: queue.RequestedDoctorID,
+		ServiceDate:       utils.AsServiceDate(queue.ServiceDate).Format(time.DateOnly),
+	}
+
+	switch queue.RoutingReason {
+	case models.QueueRoutingSameSpecialty:
+		routing.Message = "The chosen doctor is fully booked, you were assigned to another doctor of the same specialty."
+	case models.QueueRoutingGeneralPractitioner:
+		routing.Message = "The chosen doctor is not on shift, you were assigned to a general practitioner on duty."
+	case models.QueueRoutingNextDay:
+		routing.Message = fmt.Sprintf("No doctor can see you today, your appointment with the chosen doctor is on %s.", routing.ServiceDate)
+	}
+	return routing
+}
+
+// RoutingForError explains why no ticket could be handed out, nil if err is not a routing failure.
+func RoutingForError(err error) *schemas.QueueRouting {
+	if errors.Is(err, ErrDoctorFull) {
+		return &schemas.QueueRouting{
+			Reason:  models.QueueRoutingDoctorFull,
+			Message: "The chosen doctor and every doctor who could take over are fully booked.",
+		}
+	}
+	if errors.Is(err, ErrDoctorUnavailable) {
+		return &schemas.QueueRouting{
+			Reason:  models.QueueRoutingNoDoctor,
+			Message: "No doctor who could take the patient is on shift right now.",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+func TestBookingWithRequestedDoctor(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+
+	queue := s.book(t, s.createSession(t), doctor)
+	if queue.DoctorID.String() != doctor.ID || queue.RoutingReason != "" {
+		t.Errorf("expected the requested doctor without routing, got doctor %s and reason %q", queue.DoctorID, queue.RoutingReason)
+	}
+	if !queue.ServiceDate.Equal(utils.ServiceDate(time.Now())) {
+		t.Errorf("expected a ticket for today, got %s", queue.ServiceDate)
+	}
+}
+
+func TestBookingRoutesToGeneralPractitionerWhenDoctorIsOff(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	s.sendOnLeave(t, doctor)
+	gp := s.createDoctor(t, "Dr. General", "General Practitioner")
+
+	queue := s.book(t, s.createSession(t), doctor)
+	if queue.DoctorID.String() != gp.ID || queue.RoutingReason != models.QueueRoutingGeneralPractitioner {
+		t.Errorf("expected the general practitioner to take over, got doctor %s and reason %q", queue.DoctorID, queue.RoutingReason)
+	}
+	if queue.RequestedDoctorID == nil || queue.RequestedDoctorID.String() != doctor.ID {
+		t.Errorf("expected the requested doctor to be recorded, got %v", queue.RequestedDoctorID)
+	}
+}
+
+func TestBookingRoutesToSameSpecialtyWhenDoctorIsFull(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	doctor.DailyCapacity = 1
+	if err := s.store.Doctors().Save(&doctor); err != nil {
+		t.Fatalf("failed to update doctor: %v", err)
+	}
+	colleague := s.createDoctor(t, "Dr. Derm", "Dermatology")
+	s.createDoctor(t, "Dr. General", "General Practitioner")
+
+	s.book(t, s.createSession(t), doctor)
+	queue := s.book(t, s.createSession(t), doctor)
+	if queue.DoctorID.String() != colleague.ID || queue.RoutingReason != models.QueueRoutingSameSpecialty {
+		t.Errorf("expected a dermatologist to take over, got doctor %s and reason %q", queue.DoctorID, queue.RoutingReason)
+	}
+}
+
+func TestShiftCapacityCountsTicketsBookedAhead(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	doctorID := uuid.MustParse(doctor.ID)
+
+	var shifts []models.DoctorShift
+	for weekday := range 7 {
+		shifts = append(shifts, models.DoctorShift{DoctorID: doctorID, Weekday: weekday, StartTime: "00:00", EndTime: "24:00", Capacity: 1})
+	}
+	if err := s.store.Schedules().ReplaceShifts(doctorID, shifts); err != nil {
+		t.Fatalf("failed to set shifts: %v", err)
+	}
+
+	// the only place of today's shift went to a patient who booked yesterday
+	today := utils.ServiceDate(time.Now())
+	s.createTicket(t, s.createSession(t), doctor, today, today.Add(-time.Hour))
+
+	queue := s.book(t, s.createSession(t), doctor)
+	if queue.RoutingReason != models.QueueRoutingNextDay || !queue.ServiceDate.Equal(today.AddDate(0, 0, 1)) {
+		t.Errorf("expected a ticket for tomorrow, got %s with reason %q", queue.ServiceDate, queue.RoutingReason)
+	}
+}
+
+func TestShiftCapacityCountsTicketsOfTheShiftOnTheClinicClock(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	doctorID := uuid.MustParse(doctor.ID)
+
+	// tickets are stamped in the server's zone, which differs from the clinic's
+	date := utils.ServiceDate(time.Date(2025, 3, 3, 12, 0, 0, 0, config.ClinicLocation()))
+	at := func(hours, minutes int) time.Time {
+		return date.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute).In(time.UTC)
+	}
+	s.createTicket(t, s.createSession(t), doctor, date, at(7, 30))  // before the shift
+	s.createTicket(t, s.createSession(t), doctor, date, at(12, 0))  // after the shift
+	s.createTicket(t, s.createSession(t), doctor, date, at(-10, 0)) // booked the day before
+
+	window := shiftWindow{start: "08:00", end: "12:00", capacity: 2}
+	ok, err := hasCapacity(s.store, doctorID, doctor, date, window)
+	if err != nil {
+		t.Fatalf("hasCapacity failed: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected a place left with only the ticket booked ahead counting against the shift")
+	}
+
+	s.createTicket(t, s.createSession(t), doctor, date, at(9, 0))
+	ok, err = hasCapacity(s.store, doctorID, doctor, date, window)
+	if err != nil {
+		t.Fatalf("hasCapacity failed: %v", err)
+	}
+	if ok {
+		t.Error("expected the shift to be full with a ticket booked during it and one booked ahead")
+	}
+}
@@ -1,174 +1,287 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"strings"
 	"time"
 
-	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
 	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
 	"github.com/google/uuid"
 )
 
-func GenerateQueue(sessionID string, doctorID string) (*models.Queue, error) {
-	var queue models.Queue
+// QueueService hands out queue tickets, moves them through their lifecycle and publishes
+// every change to the queue event hub.
+type QueueService struct {
+	store        repositories.Store
+	hub          QueueEventHub
+	outbox       *OutboxService
+	availability *AvailabilityService
+}
+
+func NewQueueService(store repositories.Store, hub QueueEventHub, outbox *OutboxService, availability *AvailabilityService) *QueueService {
+	return &QueueService{store: store, hub: hub, outbox: outbox, availability: availability}
+}
 
+func (s *QueueService) GenerateQueue(sessionID string, doctorID string) (*models.Queue, error) {
 	// parse the sessionID and doctorID to UUID
 	sessionUUID, err := uuid.Parse(sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid session ID: %w", err)
 	}
-	queue.SessionID = sessionUUID
 
 	doctorUUID, err := uuid.Parse(doctorID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid doctor ID: %w", err)
 	}
-	queue.DoctorID = doctorUUID
 
-	// get start of the current day
-	todayStart := time.Now().Truncate(24 * time.Hour)
+	var queue *models.Queue
+	var created bool
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		queue, created, err = s.ensureQueueEntry(tx, sessionUUID, doctorUUID, "")
+		return err
+	})
+	if errors.Is(err, repositories.ErrDuplicate) {
+		// a concurrent request created the session's ticket first
+		queue, err = s.store.Queues().FindActiveBySessionID(sessionUUID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		s.publish(QueueEventTicketCreated, queue)
+	}
+
+	return queue, nil
+}
+
+// ensureQueueEntry returns the session's active ticket, creating one if there is none. The ticket is for the
+// requested doctor if they are on shift and have room, otherwise it is routed as described in assignDoctor.
+// assignmentPath records how the doctor was picked. The second return value reports whether the ticket was created.
+func (s *QueueService) ensureQueueEntry(tx repositories.Store, sessionID uuid.UUID, doctorID uuid.UUID, assignmentPath string) (*models.Queue, bool, error) {
+	existing, err := tx.Queues().FindActiveBySessionID(sessionID)
+	if err == nil {
+		return existing, false, nil
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, false, fmt.Errorf("failed to check for an existing queue entry: %w", err)
+	}
 
-	// find the latest queue entry today universally
-	var latestQueue models.Queue
-	err = config.DB.
-		Where("doctor_id = ?", doctorID).
-		Where("created_at >= ?", todayStart).
-		Order("number DESC").
-		First(&latestQueue).Error
+	// only doctors on shift with room take new patients
+	target, err := s.availability.assignDoctor(tx, doctorID, time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	target.assignmentPath = assignmentPath
 
+	queue, err := s.createQueueEntry(tx, sessionID, target)
 	if err != nil {
-		// no queue entries found today, start from 1
-		queue.Number = 1
-	} else {
-		// increment the latest queue number by 1
-		queue.Number = latestQueue.Number + 1
+		return nil, false, err
 	}
+	return queue, true, nil
+}
 
+// createQueueEntry allocates the next number of the assigned day and inserts the queue entry as part of the given
+// transaction. The counter stays locked until commit so concurrent requests for the same doctor get distinct numbers.
+// Publish the ticket_created event once the transaction has been committed.
+func (s *QueueService) createQueueEntry(tx repositories.Store, sessionID uuid.UUID, target *assignment) (*models.Queue, error) {
 	// set the created and updated time
 	now := time.Now()
-	queue.CreatedAt = now
-	queue.UpdatedAt = now
+	queue := models.Queue{
+		SessionID:     sessionID,
+		DoctorID:      target.doctorID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ServiceDate:   target.serviceDate,
+		Status:        models.QueueStatusWaiting,
+		RoutingReason: target.reason,
+
+		AssignmentPath: target.assignmentPath,
+		Priority:       target.triageReason != "",
+		TriageReason:   target.triageReason,
+	}
+	if target.reason != "" {
+		queue.RequestedDoctorID = &target.requestedID
+	}
 
-	// insert the queue entry into the database
-	err = config.DB.Create(&queue).Error
+	number, err := tx.Queues().AllocateNumber(queue.DoctorID, queue.ServiceDate)
 	if err != nil {
+		return nil, fmt.Errorf("failed to allocate queue number: %w", err)
+	}
+	queue.Number = number
+
+	// the doctor was checked before the counter was locked, a concurrent booking may have taken the last place.
+	// Emergencies are seen whether there is room or not.
+	if !queue.Priority {
+		ok, err := hasCapacity(tx, target.doctorID, target.doctor, target.serviceDate, target.window)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrDoctorFull
+		}
+	}
+
+	// insert the queue entry into the database
+	if err := tx.Queues().Create(&queue); err != nil {
 		return nil, fmt.Errorf("failed to create queue entry: %w", err)
 	}
 
 	return &queue, nil
 }
 
-func GetCurrentQueue(doctorID uuid.UUID) (*models.Queue, error) {
-	todayStart := time.Now().Truncate(24 * time.Hour)
+// GetCurrentQueue returns the ticket the doctor is serving today, the most recently called one that is
+// not finished yet, or the next waiting ticket if nobody has been called.
+func (s *QueueService) GetCurrentQueue(doctorID uuid.UUID) (*models.Queue, error) {
+	return s.currentQueue(s.store, doctorID)
+}
 
-	var queue models.Queue
-	err := config.DB.
-		Joins("JOIN sessions ON sessions.id = queues.session_id").
-		Where("queues.doctor_id = ?", doctorID).
-		Where("queues.created_at >= ?", todayStart).
-		Where("sessions.doctor_diagnosis = ''").
-		Order("queues.number ASC").
-		Preload("Session"). // optional: preload session if you need it
-		First(&queue).Error
+func (s *QueueService) currentQueue(store repositories.Store, doctorID uuid.UUID) (*models.Queue, error) {
+	today := utils.ServiceDate(time.Now())
+
+	queue, err := store.Queues().FindServing(doctorID, today)
+	if errors.Is(err, repositories.ErrNotFound) {
+		queue, err = store.Queues().FindNextWaiting(doctorID, today)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("no queue found for today: %w", err)
 	}
 
-	return &queue, nil
+	return queue, nil
 }
 
-func GetTotalAppointments(doctorID uuid.UUID) int {
-	var count int64
-	config.DB.Model(&models.Queue{}).Where("doctor_id = ?", doctorID).Count(&count)
-	return int(count)
+// QueueTicket returns the ticket as patients see it, nil if queue is nil
+func QueueTicket(queue *models.Queue) *schemas.QueueTicket {
+	if queue == nil {
+		return nil
+	}
+	return &schemas.QueueTicket{
+		ID:                   queue.ID,
+		Number:               queue.Number,
+		Status:               queue.Status,
+		EstimatedWaitMinutes: queue.EstimatedWaitMinutes,
+	}
 }
 
-func GetDailyAppointments(doctorID uuid.UUID) int {
-	var count int64
-	today := time.Now().Truncate(24 * time.Hour)
-	config.DB.Model(&models.Queue{}).Where("doctor_id = ? AND created_at >= ?", doctorID, today).Count(&count)
-	return int(count)
+func (s *QueueService) GetTotalAppointments(doctorID uuid.UUID) int {
+	count, _ := s.store.Queues().Count(repositories.QueueFilter{DoctorID: doctorID})
+	return count
 }
 
-func SendQueueEmail(to string, queue int, currentQueue int, token string, doctor models.Doctor) (map[string]interface{}, error) {
-	email := schemas.Email{
-		To:      to,
-		Subject: "Queue Notification",
-		Body:    "This is your queue number",
-		From:    "omsehat@sportsnow.app",
-		HTML:    injectQueueIntoHTML(queue, currentQueue, doctor),
-	}
+func (s *QueueService) GetDailyAppointments(doctorID uuid.UUID) int {
+	today := utils.ServiceDate(time.Now())
+	count, _ := s.store.Queues().Count(repositories.QueueFilter{DoctorID: doctorID, ServiceDate: &today})
+	return count
+}
 
-	// URL of the email service
-	url := "http://52.230.88.220:16250/send-email"
+// Subscribe subscribes to one doctor's queue events, or the whole clinic's if doctorID is nil.
+func (s *QueueService) Subscribe(doctorID *uuid.UUID) (<-chan schemas.QueueEvent, func()) {
+	return s.hub.Subscribe(doctorID)
+}
 
-	jsonData, err := json.Marshal(email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal email request: %w", err)
+// publish notifies board subscribers about a ticket change
+func (s *QueueService) publish(eventType string, queue *models.Queue) {
+	s.hub.Publish(schemas.QueueEvent{
+		Type:     eventType,
+		DoctorID: queue.DoctorID,
+		QueueID:  queue.ID,
+		Number:   queue.Number,
+		Status:   queue.Status,
+		At:       time.Now(),
+	})
+}
+
+// enqueueQueueTicket stores the queue ticket message for the user's preferred channel in the outbox as part of the given transaction.
+// The queue entry must have its doctor loaded.
+func (s *QueueService) enqueueQueueTicket(tx repositories.Store, user *models.User, queue *models.Queue, currentQueue int) error {
+	data := queueTemplateData{
+		QueueNumber:        queue.Number,
+		CurrentQueueNumber: currentQueue,
+		EstimatedWait:      formatEstimatedWait(queue.EstimatedWaitMinutes, user.Language),
+		DoctorName:         queue.Doctor.Name,
+		DoctorSpecialty:    queue.Doctor.Specialty,
+		RoomNumber:         queue.Doctor.Roomno,
+	}
+	if bookedAhead(queue) {
+		data.ServiceDate = formatServiceDate(utils.AsServiceDate(queue.ServiceDate), user.Language)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	notification, err := newUserNotification(user, schemas.NotificationKindQueue, templateQueue, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	return s.outbox.Enqueue(tx, notification)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// enqueueQueueNearAlert stores the "your turn is near" message for the user's preferred channel in the outbox
+// as part of the given transaction
+func (s *QueueService) enqueueQueueNearAlert(tx repositories.Store, user *models.User, queue int, positionsAhead int, estimatedWait *int, doctor models.Doctor) error {
+	notification, err := newUserNotification(user, schemas.NotificationKindQueueNear, templateQueueNear, queueNearTemplateData{
+		QueueNumber:     queue,
+		PositionsAhead:  positionsAhead,
+		EstimatedWait:   formatEstimatedWait(estimatedWait, user.Language),
+		DoctorName:      doctor.Name,
+		DoctorSpecialty: doctor.Specialty,
+		RoomNumber:      doctor.Roomno,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("error from email service: %s", resp.Status)
+		return err
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return s.outbox.Enqueue(tx, notification)
+}
 
-	return result, nil
+// bookedAhead reports whether the ticket is for a later day than today
+func bookedAhead(queue *models.Queue) bool {
+	return utils.AsServiceDate(queue.ServiceDate).After(utils.ServiceDate(time.Now()))
 }
 
-func injectQueueIntoHTML(queue int, currentQueue int, doctor models.Doctor) string {
-	// Path to the HTML file
-	htmlFilePath := "emails/queue_mail.html"
+var (
+	indonesianWeekdays = []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+	indonesianMonths   = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+)
 
-	// Read the HTML file
-	htmlBytes, err := os.ReadFile(htmlFilePath)
-	if err != nil {
-		log.Fatalf("Failed to read HTML file: %v", err)
+// formatServiceDate renders the day of a ticket for humans in the given language
+func formatServiceDate(date time.Time, language string) string {
+	if normalizeLanguage(language, defaultLanguage()) == models.LanguageIndonesian {
+		return fmt.Sprintf("%s, %d %s %d", indonesianWeekdays[date.Weekday()], date.Day(), indonesianMonths[date.Month()-1], date.Year())
 	}
+	return date.Format("Monday, 2 January 2006")
+}
 
-	// Convert the file content to a string
-	htmlString := string(htmlBytes)
-
-	htmlString = strings.ReplaceAll(htmlString, "{{queue_number}}", fmt.Sprintf("%d", queue))
-	htmlString = strings.ReplaceAll(htmlString, "{{current_queue_number}}", fmt.Sprintf("%d", currentQueue))
-	htmlString = strings.ReplaceAll(htmlString, "{{doctor_name}}", doctor.Name)
-	htmlString = strings.ReplaceAll(htmlString, "{{doctor_specialty}}", doctor.Specialty)
-	htmlString = strings.ReplaceAll(htmlString, "{{room_number}}", doctor.Roomno)
+// formatEstimatedWait renders the estimated wait for humans in the given language
+func formatEstimatedWait(minutes *int, language string) string {
+	if normalizeLanguage(language, defaultLanguage()) == models.LanguageIndonesian {
+		switch {
+		case minutes == nil:
+			return "-"
+		case *minutes < 1:
+			return "kurang dari satu menit"
+		default:
+			return fmt.Sprintf("sekitar %d menit", *minutes)
+		}
+	}
 
-	return htmlString
+	switch {
+	case minutes == nil:
+		return "-"
+	case *minutes < 1:
+		return "less than a minute"
+	case *minutes == 1:
+		return "about 1 minute"
+	default:
+		return fmt.Sprintf("about %d minutes", *minutes)
+	}
 }
 
-func GetQueueBySessionID(sessionID uuid.UUID) *models.Queue {
-	var queue models.Queue
-	err := config.DB.Where("session_id = ?", sessionID).Order("created_at DESC").First(&queue).Error
+func (s *QueueService) GetQueueBySessionID(sessionID uuid.UUID) *models.Queue {
+	queue, err := s.store.Queues().FindLatestBySessionID(sessionID)
 	if err != nil {
 		return nil
 	}
-	return &queue
+	return queue
 }
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+func TestParallelBookingsGetDistinctGapFreeNumbers(t *testing.T) {
+	const bookings = 50
+
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+
+	sessions := make([]*models.Session, bookings)
+	for i := range sessions {
+		sessions[i] = s.createSession(t)
+	}
+
+	numbers := make([]int, bookings)
+	errs := make([]error, bookings)
+	var wg sync.WaitGroup
+	for i, session := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queue, err := s.queues.GenerateQueue(session.ID.String(), doctor.ID)
+			if err == nil {
+				numbers[i] = queue.Number
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("booking %d failed: %v", i, err)
+		}
+	}
+
+	slices.Sort(numbers)
+	for i, number := range numbers {
+		if number != i+1 {
+			t.Fatalf("expected numbers 1 to %d without gaps or duplicates, got %v", bookings, numbers)
+		}
+	}
+}
+
+func TestQueueNumbersArePerDoctorAndDay(t *testing.T) {
+	s := newTestServices(t)
+	first := s.createDoctor(t, "Dr. General", "General Practitioner")
+	second := s.createDoctor(t, "Dr. Skin", "Dermatology")
+
+	today := utils.ServiceDate(time.Now())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	tests := []struct {
+		doctor models.Doctor
+		date   time.Time
+		want   int
+	}{
+		{doctor: first, date: today, want: 1},
+		{doctor: first, date: today, want: 2},
+		{doctor: second, date: today, want: 1},
+		{doctor: first, date: tomorrow, want: 1},
+		{doctor: first, date: today, want: 3},
+	}
+
+	for _, tt := range tests {
+		number, err := s.store.Queues().AllocateNumber(uuid.MustParse(tt.doctor.ID), tt.date)
+		if err != nil {
+			t.Fatalf("AllocateNumber failed: %v", err)
+		}
+		if number != tt.want {
+			t.Errorf("expected number %d for %s on %s, got %d", tt.want, tt.doctor.Name, tt.date.Format(time.DateOnly), number)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQueueNotFound          = errors.New("queue entry not found")
+	ErrQueueEmpty             = errors.New("no waiting patients in the queue")
+	ErrInvalidQueueTransition = errors.New("invalid queue status transition")
+)
+
+// queueTransitions lists the statuses a queue entry can move to from each status
+// (waiting can go straight to done when a doctor diagnoses a patient without calling them first)
+var queueTransitions = map[string][]string{
+	models.QueueStatusWaiting:        {models.QueueStatusCalled, models.QueueStatusDone, models.QueueStatusSkipped, models.QueueStatusCancelled},
+	models.QueueStatusCalled:         {models.QueueStatusCalled, models.QueueStatusInConsultation, models.QueueStatusDone, models.QueueStatusSkipped, models.QueueStatusCancelled},
+	models.QueueStatusInConsultation: {models.QueueStatusDone},
+	models.QueueStatusSkipped:        {models.QueueStatusCalled, models.QueueStatusCancelled},
+}
+
+func canTransitionQueue(from string, to string) bool {
+	return slices.Contains(queueTransitions[from], to)
+}
+
+// applyQueueStatus sets the status and the timestamp of the matching transition
+func applyQueueStatus(queue *models.Queue, status string, now time.Time) {
+	queue.Status = status
+	queue.UpdatedAt = now
+
+	switch status {
+	case models.QueueStatusCalled:
+		queue.CalledAt = &now
+	case models.QueueStatusInConsultation:
+		queue.ConsultationStartedAt = &now
+	case models.QueueStatusDone:
+		queue.CompletedAt = &now
+	case models.QueueStatusSkipped:
+		queue.SkippedAt = &now
+	case models.QueueStatusCancelled:
+		queue.CancelledAt = &now
+	}
+}
+
+// CallNextQueue calls the doctor's next waiting ticket of the day, priority tickets first. Tickets that were
+// called but whose consultation never started are marked as no-shows, they can still be recalled.
+func (s *QueueService) CallNextQueue(doctorID uuid.UUID) (*models.Queue, error) {
+	var queue *models.Queue
+	var skipped []models.Queue
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		today := utils.ServiceDate(time.Now())
+
+		// lock the next ticket so two concurrent calls do not pick the same one
+		var err error
+		queue, err = tx.Queues().LockNextWaiting(doctorID, today)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrQueueEmpty
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch next queue entry: %w", err)
+		}
+
+		// the doctor moved on without seeing the patients called before
+		skipped, err = tx.Queues().Find(repositories.QueueFilter{
+			DoctorID:    doctorID,
+			ServiceDate: &today,
+			Statuses:    []string{models.QueueStatusCalled},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch called queue entries: %w", err)
+		}
+
+		now := time.Now()
+		for i := range skipped {
+			applyQueueStatus(&skipped[i], models.QueueStatusSkipped, now)
+			if err := tx.Queues().Save(&skipped[i]); err != nil {
+				return fmt.Errorf("failed to update queue entry: %w", err)
+			}
+		}
+
+		applyQueueStatus(queue, models.QueueStatusCalled, now)
+		if err := tx.Queues().Save(queue); err != nil {
+			return fmt.Errorf("failed to update queue entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range skipped {
+		s.publish(QueueEventSkipped, &skipped[i])
+	}
+	s.publish(QueueEventNowServing, queue)
+
+	return queue, nil
+}
+
+// TransitionQueue moves one of the doctor's queue entries to a new status.
+func (s *QueueService) TransitionQueue(doctorID uuid.UUID, queueID uuid.UUID, status string) (*models.Queue, error) {
+	var queue *models.Queue
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		queue, err = tx.Queues().LockByID(queueID, doctorID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrQueueNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch queue entry: %w", err)
+		}
+
+		if !canTransitionQueue(queue.Status, status) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidQueueTransition, queue.Status, status)
+		}
+
+		applyQueueStatus(queue, status, time.Now())
+		if err := tx.Queues().Save(queue); err != nil {
+			return fmt.Errorf("failed to update queue entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(queueStatusEventType(status), queue)
+
+	return queue, nil
+}
+
+func (s *QueueService) GetQueueByID(queueID uuid.UUID) *models.Queue {
+	queue, err := s.store.Queues().FindByID(queueID)
+	if err != nil {
+		return nil
+	}
+	return queue
+}
+
+// GetQueueBoard returns today's board state for the given doctor, or for every active doctor if doctorID is nil.
+func (s *QueueService) GetQueueBoard(doctorID *uuid.UUID) ([]schemas.QueueBoardEntry, error) {
+	var doctors []models.Doctor
+	if doctorID != nil {
+		doctor, err := s.store.Doctors().FindByID(*doctorID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return nil, fmt.Errorf("failed to fetch doctors: %w", err)
+		} else if err == nil {
+			doctors = append(doctors, *doctor)
+		}
+	} else {
+		var err error
+		doctors, err = s.store.Doctors().FindActive()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch doctors: %w", err)
+		}
+	}
+
+	today := utils.ServiceDate(time.Now())
+	board := make([]schemas.QueueBoardEntry, 0, len(doctors))
+
+	for _, doctor := range doctors {
+		entry := schemas.QueueBoardEntry{
+			DoctorID:   doctor.ID,
+			DoctorName: doctor.Name,
+			Specialty:  doctor.Specialty,
+			Roomno:     doctor.Roomno,
+		}
+
+		doctorUUID, err := uuid.Parse(doctor.ID)
+		if err != nil {
+			continue
+		}
+
+		// the most recently called ticket that is not finished yet
+		if serving, err := s.store.Queues().FindServing(doctorUUID, today); err == nil {
+			entry.NowServing = &serving.Number
+		}
+
+		entry.Waiting, _ = s.store.Queues().Count(repositories.QueueFilter{
+			DoctorID:    doctorUUID,
+			ServiceDate: &today,
+			Statuses:    []string{models.QueueStatusWaiting},
+		})
+
+		board = append(board, entry)
+	}
+
+	return board, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/google/uuid"
+)
+
+func TestCallNextQueueSkipsPatientsCalledBefore(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+	doctorID := uuid.MustParse(doctor.ID)
+
+	first := s.book(t, s.createSession(t), doctor)
+	second := s.book(t, s.createSession(t), doctor)
+	third := s.book(t, s.createSession(t), doctor)
+
+	for _, want := range []*models.Queue{first, second} {
+		called, err := s.queues.CallNextQueue(doctorID)
+		if err != nil {
+			t.Fatalf("CallNextQueue failed: %v", err)
+		}
+		if called.ID != want.ID {
+			t.Fatalf("expected ticket %d to be called, got %d", want.Number, called.Number)
+		}
+	}
+
+	stored, err := s.store.Queues().FindByID(first.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch ticket: %v", err)
+	}
+	if stored.Status != models.QueueStatusSkipped {
+		t.Errorf("expected the earlier called ticket to be skipped, got %s", stored.Status)
+	}
+
+	// only the patient being called is ahead of the waiting ticket
+	if active := countActiveQueue(s.store, doctorID, third.ServiceDate); active != 1 {
+		t.Errorf("expected one ticket being served, got %d", active)
+	}
+
+	if _, err := s.queues.TransitionQueue(doctorID, first.ID, models.QueueStatusCalled); err != nil {
+		t.Errorf("expected the skipped ticket to be recalled: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// testServices wires the services to a MemoryStore and the fake LLM provider. The outbox worker is not started,
+// notifications stay pending in the store.
+type testServices struct {
+	store        *repositories.MemoryStore
+	outbox       *OutboxService
+	users        *UserService
+	availability *AvailabilityService
+	queues       *QueueService
+	sessions     *SessionService
+	idempotency  *IdempotencyService
+}
+
+// newTestServices builds the services, the fake LLM replays the given responses or its default script if there
+// are none
+func newTestServices(t *testing.T, script ...schemas.LLMResponse) *testServices {
+	t.Helper()
+
+	llm, err := NewFakeProvider("")
+	if err != nil {
+		t.Fatalf("failed to create fake LLM provider: %v", err)
+	}
+	if len(script) > 0 {
+		llm.script = script
+	}
+
+	store := repositories.NewMemoryStore()
+	notifier := NewChannelNotifier(map[string]Notifier{
+		schemas.NotificationChannelEmail: NewFileNotifier(t.TempDir(), "test@omsehat.local"),
+	})
+	outbox := NewOutboxService(store, notifier)
+	availability := NewAvailabilityService(store)
+	queues := NewQueueService(store, NewInProcessQueueHub(), outbox, availability)
+
+	return &testServices{
+		store:        store,
+		outbox:       outbox,
+		users:        NewUserService(store, outbox),
+		availability: availability,
+		queues:       queues,
+		sessions:     NewSessionService(store, llm, queues, availability, outbox),
+		idempotency:  NewIdempotencyService(store),
+	}
+}
+
+// createDoctor stores an active doctor, who works all day as they have no weekly shifts
+func (s *testServices) createDoctor(t *testing.T, name string, specialty string) models.Doctor {
+	t.Helper()
+
+	doctor := models.Doctor{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Email:     uuid.NewString() + "@omsehat.local",
+		Specialty: specialty,
+		Roomno:    "101",
+	}
+	if err := s.store.Doctors().Create(&doctor); err != nil {
+		t.Fatalf("failed to create doctor: %v", err)
+	}
+	return doctor
+}
+
+// sendOnLeave puts the doctor on leave today, so they are not on shift
+func (s *testServices) sendOnLeave(t *testing.T, doctor models.Doctor) {
+	t.Helper()
+
+	today := utils.ServiceDate(time.Now())
+	leave := models.DoctorLeave{
+		ID:        uuid.New(),
+		DoctorID:  uuid.MustParse(doctor.ID),
+		StartDate: today,
+		EndDate:   today,
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.Schedules().CreateLeave(&leave); err != nil {
+		t.Fatalf("failed to create leave: %v", err)
+	}
+}
+
+// createSession stores a patient and a session of theirs, with the user loaded like the controllers do
+func (s *testServices) createSession(t *testing.T) *models.Session {
+	t.Helper()
+
+	now := time.Now()
+	user := models.User{
+		ID:        uuid.New(),
+		Name:      "Patient",
+		DOB:       "1990-01-01",
+		Email:     uuid.NewString() + "@omsehat.local",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.Users().Create(&user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	session := models.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Heartrate: 80,
+		Bodytemp:  36.8,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.Sessions().Create(&session); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	session.User = user
+	return &session
+}
+
+// outboxCount returns the number of pending notifications of the given kind
+func (s *testServices) outboxCount(t *testing.T, kind string) int {
+	t.Helper()
+
+	messages, err := s.store.Outbox().List(models.OutboxStatusPending, 1000)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+
+	count := 0
+	for _, message := range messages {
+		if message.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// book gives the session a ticket with the doctor as if the LLM had chosen them
+func (s *testServices) book(t *testing.T, session *models.Session, doctor models.Doctor) *models.Queue {
+	t.Helper()
+
+	queue, _, err := s.sessions.ApplyLLMResponse(session, "I have a cold", schemas.LLMResponse{
+		NextAction:   "APPOINTMENT",
+		Reply:        "Please see the doctor.",
+		DoctorID:     doctor.ID,
+		PreDiagnosis: "Common cold",
+	})
+	if err != nil {
+		t.Fatalf("failed to book: %v", err)
+	}
+	if queue == nil {
+		t.Fatal("expected a ticket")
+	}
+	return queue
+}
+
+// createTicket stores a waiting ticket for the session with the doctor on the service date, created at the given
+// time, as if it had been booked ahead
+func (s *testServices) createTicket(t *testing.T, session *models.Session, doctor models.Doctor, serviceDate time.Time, createdAt time.Time) *models.Queue {
+	t.Helper()
+
+	doctorID := uuid.MustParse(doctor.ID)
+	number, err := s.store.Queues().AllocateNumber(doctorID, serviceDate)
+	if err != nil {
+		t.Fatalf("failed to allocate queue number: %v", err)
+	}
+
+	queue := models.Queue{
+		ID:          uuid.New(),
+		DoctorID:    doctorID,
+		SessionID:   session.ID,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		ServiceDate: serviceDate,
+		Number:      number,
+		Status:      models.QueueStatusWaiting,
+	}
+	if err := s.store.Queues().Create(&queue); err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+	return &queue
+}
@@ -2,118 +2,290 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
 	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/Om-SEHAT/omsehat-api/utils"
 	"github.com/google/uuid"
-	"google.golang.org/genai"
-	"gorm.io/gorm"
 )
 
-func convertMessageToGenaiContent(message models.Message) *genai.Content {
-	// Determine the role of the message
-	var role genai.Role
-	if message.Role == "user" {
-		role = genai.RoleUser
-	} else if message.Role == "omsapa" {
-		role = genai.RoleModel
-	} else {
-		return nil // Invalid role, return nil or handle error as needed
-	}
+var (
+	ErrNotAssignedDoctor = errors.New("session is not assigned to this doctor")
+	ErrInvalidNextAction = errors.New("Invalid next action")
+)
+
+// SessionService runs the chat sessions between patients and the LLM.
+type SessionService struct {
+	store        repositories.Store
+	llm          LLMProvider
+	queues       *QueueService
+	availability *AvailabilityService
+	outbox       *OutboxService
+}
+
+func NewSessionService(store repositories.Store, llm LLMProvider, queues *QueueService, availability *AvailabilityService, outbox *OutboxService) *SessionService {
+	return &SessionService{store: store, llm: llm, queues: queues, availability: availability, outbox: outbox}
+}
+
+// GetLLMResponse asks the LLM for the next reply of the session. Messages and vitals with a red flag skip the
+// LLM and get an EMERGENCY response right away, as do responses the LLM rates as an emergency. Unusable output
+// is sent back to the LLM for repair, see generateResponse, and appointments are checked to go to an active
+// doctor, see checkDoctorChoice.
+func (s *SessionService) GetLLMResponse(newMessage string, session *models.Session) (schemas.LLMResponse, error) {
+	return s.respond(context.Background(), newMessage, session, nil)
+}
 
-	// Create a new genai.Content object from the message content and role
-	content := genai.NewContentFromText(message.Content, role)
-	return content
+// StreamLLMResponse works like GetLLMResponse but calls onReply with the reply text as it is generated.
+func (s *SessionService) StreamLLMResponse(ctx context.Context, newMessage string, session *models.Session, onReply func(delta string)) (schemas.LLMResponse, error) {
+	return s.respond(ctx, newMessage, session, onReply)
 }
 
-func GetLLMResponse(newMessage string, session *models.Session) (string, error) {
-	// initialize the Gemini client with your API key and backend
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"),
-		Backend: genai.BackendGeminiAPI,
-	})
+func (s *SessionService) respond(ctx context.Context, newMessage string, session *models.Session, onReply func(delta string)) (schemas.LLMResponse, error) {
+	// red flags bypass the chat
+	if triageReason, ok := triageMessage(session, newMessage); ok {
+		response := emergencyResponse(session, triageReason, "")
+		if onReply != nil {
+			onReply(response.Reply)
+		}
+		return response, nil
+	}
+
+	request := s.buildLLMRequest(newMessage, session)
 
+	response, err := s.generateResponse(ctx, request, onReply)
 	if err != nil {
-		log.Printf("Error creating Gemini client: %v\n", err)
-		return "", fmt.Errorf("error creating LLM client: %w", err)
+		return schemas.LLMResponse{}, err
 	}
+	if isEmergencyResponse(response) {
+		return emergencyResponse(session, "LLM rated the symptoms as an emergency", response.PreDiagnosis), nil
+	}
+	if err := s.checkDoctorChoice(ctx, request, &response); err != nil {
+		return schemas.LLMResponse{}, err
+	}
+	return response, nil
+}
 
-	// the chat history is stored as a one-to-many relationship in the database
-	var storedHistory []models.Message = session.Messages
+// generateResponse asks the LLM for a response, streaming it to onReply if set, and asks it to repair an
+// unusable one up to LLM_REPAIR_ATTEMPTS times. Repairs are not streamed, the reply of a repaired response
+// replaces whatever was streamed. It returns an LLMOutputError if the output is still unusable.
+func (s *SessionService) generateResponse(ctx context.Context, request LLMRequest, onReply func(delta string)) (schemas.LLMResponse, error) {
+	var response schemas.LLMResponse
+	var err error
+	if onReply != nil {
+		response, err = s.llm.StreamResponse(ctx, request, onReply)
+	} else {
+		response, err = s.llm.GenerateResponse(ctx, request)
+	}
 
-	// build the genai history
-	var genaiHistory []*genai.Content
+	for attempt := 1; ; attempt++ {
+		if err == nil {
+			err = validateLLMResponse(response)
+		}
+
+		var outputErr *LLMOutputError
+		if !errors.As(err, &outputErr) || attempt > llmRepairAttempts() {
+			return response, err
+		}
+
+		log.Printf("Unusable LLM output (%s), asking for a repair (attempt %d)\n", outputErr.Reason, attempt)
+		response, err = s.llm.GenerateResponse(ctx, repairRequest(request, outputErr))
+	}
+}
 
+func (s *SessionService) buildLLMRequest(newMessage string, session *models.Session) LLMRequest {
 	// build the system prompt using the session data
-	systemPromptText := buildSystemPrompt(session)
+	systemPromptText := s.buildSystemPrompt(session)
 	log.Printf("System Prompt: %s\n", systemPromptText)
 
-	var temperature float32 = 0.8
-	var TopP float32 = 0.95
-	config := &genai.GenerateContentConfig{
-		SystemInstruction: genai.NewContentFromText(systemPromptText, genai.RoleUser),
-		ResponseMIMEType:  "application/json",
-		TopP:              &TopP,
-		Temperature:       &temperature,
-		MaxOutputTokens:   8192,
-		ResponseSchema: &genai.Schema{
-			Type: genai.TypeObject,
-			Properties: map[string]*genai.Schema{
-				"next_action":  {Type: genai.TypeString, Enum: []string{"CONTINUE_CHAT", "APPOINTMENT"}},
-				"reply":        {Type: genai.TypeString},
-				"doctor_id":    {Type: genai.TypeString},
-				"prediagnosis": {Type: genai.TypeString},
-			},
-			Required: []string{"next_action", "reply", "doctor_id", "prediagnosis"},
-		},
-	}
-
-	// add the stored messages to the history
-	for _, messageItem := range storedHistory {
-		content := convertMessageToGenaiContent(messageItem)
-		if content != nil {
-			genaiHistory = append(genaiHistory, content)
+	// the chat history is stored as a one-to-many relationship in the database
+	history := make([]schemas.Message, 0, len(session.Messages))
+	for _, messageItem := range session.Messages {
+		history = append(history, schemas.Message{Role: messageItem.Role, Content: messageItem.Content})
+	}
+
+	return LLMRequest{
+		SystemPrompt: systemPromptText,
+		History:      history,
+		NewMessage:   newMessage,
+	}
+}
+
+// ApplyLLMResponse carries out the next action chosen by the LLM and saves the new messages to the chat history.
+// For appointments it returns the session's queue entry and the doctor's current queue, which is nil when the
+// ticket was booked for a later day. Emergencies get a priority ticket and alert the staff, the queue entry is nil
+// if no doctor is on shift.
+// Everything is written in one transaction, and a session keeps a single active ticket, so retrying a
+// request that booked an appointment returns the ticket booked the first time.
+func (s *SessionService) ApplyLLMResponse(session *models.Session, newMessage string, LLMResponse schemas.LLMResponse) (*models.Queue, *models.Queue, error) {
+	// from the LLM response determine the next action
+	log.Println("LLM Response Next Action:", LLMResponse.NextAction)
+	log.Println("-----------------------------------")
+	log.Println("LLM Response Doctor ID:", LLMResponse.DoctorID)
+	log.Println("-----------------------------------")
+	log.Println("LLM Response:", LLMResponse.Reply)
+
+	var doctorUUID uuid.UUID
+	switch LLMResponse.NextAction {
+	case "CONTINUE_CHAT":
+		// just continue
+	case "APPOINTMENT":
+		var err error
+		doctorUUID, err = uuid.Parse(LLMResponse.DoctorID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid doctor ID: %w", err)
+		}
+	case "EMERGENCY":
+		// handled below
+	default:
+		return nil, nil, ErrInvalidNextAction
+	}
+
+	var queue, currentQueue *models.Queue
+	var created, flagged bool
+	apply := func(tx repositories.Store) error {
+		var err error
+		switch LLMResponse.NextAction {
+		case "APPOINTMENT":
+			queue, currentQueue, created, err = s.bookAppointment(tx, session, doctorUUID, LLMResponse.AssignmentPath, LLMResponse.PreDiagnosis)
+		case "EMERGENCY":
+			queue, currentQueue, created, flagged, err = s.bookEmergency(tx, session, newMessage, LLMResponse)
+		}
+		if err != nil {
+			return err
+		}
+
+		// update the chat history with the new message and LLM response
+		return saveChatHistory(tx, session.ID, newMessage, LLMResponse.Reply)
+	}
+
+	err := s.store.Transaction(apply)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		// a concurrent request booked the session's ticket first, run again to pick it up
+		err = s.store.Transaction(apply)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if created {
+		s.queues.publish(QueueEventTicketCreated, queue)
+	}
+	if flagged {
+		s.queues.publishEmergency(queue)
+	}
+	if created || flagged {
+		s.outbox.Wake()
+	}
+
+	return queue, currentQueue, nil
+}
+
+// bookAppointment gives the session a ticket for the doctor, queues the ticket message and saves the
+// prediagnosis as part of the given transaction. If the session already has an active ticket it is
+// returned unchanged, created reports whether a new ticket was made.
+func (s *SessionService) bookAppointment(tx repositories.Store, session *models.Session, doctorID uuid.UUID, assignmentPath string, prediagnosis string) (queue *models.Queue, currentQueue *models.Queue, created bool, err error) {
+	queue, created, err = s.queues.ensureQueueEntry(tx, session.ID, doctorID, assignmentPath)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return s.completeBooking(tx, session, queue, created, prediagnosis)
+}
+
+// bookEmergency gives the session a priority ticket and, the first time the session is flagged, alerts the staff
+// as part of the given transaction. The queue entry is nil if no ticket could be handed out, the staff are alerted
+// anyway. flagged reports whether the ticket became a priority ticket or staff were alerted, publish the emergency
+// event once the transaction has been committed.
+func (s *SessionService) bookEmergency(tx repositories.Store, session *models.Session, newMessage string, LLMResponse schemas.LLMResponse) (queue *models.Queue, currentQueue *models.Queue, created bool, flagged bool, err error) {
+	queue, created, flagged, err = s.queues.ensurePriorityEntry(tx, session.ID, LLMResponse.TriageReason)
+	if errors.Is(err, ErrDoctorUnavailable) {
+		log.Printf("No doctor is on shift for the emergency in session %s\n", session.ID)
+	} else if err != nil {
+		return nil, nil, false, false, err
+	}
+
+	if queue != nil {
+		prediagnosis := LLMResponse.PreDiagnosis
+		if prediagnosis == "" {
+			prediagnosis = "Emergency: " + LLMResponse.TriageReason
+		}
+		queue, currentQueue, created, err = s.completeBooking(tx, session, queue, created, prediagnosis)
+		if err != nil {
+			return nil, nil, false, false, err
 		}
 	}
 
-	chat, err := client.Chats.Create(ctx, os.Getenv("GEMINI_MODEL"), config, genaiHistory)
+	// alert the staff once per session, even if the patient got no ticket
+	now := time.Now()
+	alert, err := tx.Sessions().MarkEmergencyAlerted(session.ID, now)
 	if err != nil {
-		log.Printf("Error creating chat: %v\n", err)
-		return "", fmt.Errorf("error creating chat: %w", err)
+		return nil, nil, false, false, fmt.Errorf("failed to flag session: %w", err)
+	}
+	if alert {
+		session.EmergencyAlertedAt = &now
+		if err := s.queues.enqueueStaffAlerts(tx, session, queue, LLMResponse.TriageReason, newMessage); err != nil {
+			return nil, nil, false, false, err
+		}
 	}
+	return queue, currentQueue, created, flagged || alert, nil
+}
 
-	res, err := chat.SendMessage(ctx, genai.Part{Text: newMessage})
+// completeBooking loads the ticket's details and, for a new ticket, queues the ticket message and saves the
+// prediagnosis as part of the given transaction
+func (s *SessionService) completeBooking(tx repositories.Store, session *models.Session, queue *models.Queue, created bool, prediagnosis string) (*models.Queue, *models.Queue, bool, error) {
+	// load queue's doctor
+	queue, err := tx.Queues().FindWithDetails(queue.ID)
 	if err != nil {
-		log.Printf("Error sending message: %v\n", err)
-		return "", fmt.Errorf("error sending message: %w", err)
+		return nil, nil, false, fmt.Errorf("failed to load queue entry: %w", err)
+	}
+
+	// a ticket booked for a later day has no current queue yet
+	var currentQueue *models.Queue
+	var currentNumber int
+	if !bookedAhead(queue) {
+		currentQueue, err = s.queues.currentQueue(tx, queue.DoctorID)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		currentNumber = currentQueue.Number
 	}
+	s.queues.estimateWait(tx, queue)
 
-	// get the response from the LLM
-	if res != nil && len(res.Candidates) > 0 && res.Candidates[0].Content != nil &&
-		len(res.Candidates[0].Content.Parts) > 0 {
-		text := res.Candidates[0].Content.Parts[0].Text
-		return text, nil
+	if !created {
+		return queue, currentQueue, false, nil
 	}
 
-	return "", fmt.Errorf("no response from LLM")
+	// queue the ticket message to the user
+	err = s.queues.enqueueQueueTicket(tx, &session.User, queue, currentNumber)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	// update the session's prediagnosis
+	session.Prediagnosis = prediagnosis
+	if err := tx.Sessions().Save(session); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to save prediagnosis: %w", err)
+	}
+
+	return queue, currentQueue, true, nil
 }
 
-func UpdateChatHistory(sessionId string, newMessage string, LLMResponse string) error {
+func (s *SessionService) UpdateChatHistory(sessionID uuid.UUID, newMessage string, LLMResponse string) error {
+	return saveChatHistory(s.store, sessionID, newMessage, LLMResponse)
+}
 
+// saveChatHistory appends the user's message and the LLM's reply to the session's chat history
+func saveChatHistory(store repositories.Store, sessionID uuid.UUID, newMessage string, LLMResponse string) error {
 	// get the session from the database
-	var session models.Session
-	err := config.DB.Where("id = ?", sessionId).First(&session).Error
+	session, err := store.Sessions().FindByID(sessionID)
 	if err != nil {
-		return fmt.Errorf("error fetching session: %v", err)
+		return fmt.Errorf("error fetching session: %w", err)
 	}
 
 	// append the new message and LLM response to the chat history
@@ -122,18 +294,18 @@ func UpdateChatHistory(sessionId string, newMessage string, LLMResponse string)
 	newLLMResponse := models.Message{Role: "omsapa", Content: LLMResponse, SessionID: session.ID, CreatedAt: now.Add(time.Millisecond), UpdatedAt: now.Add(time.Millisecond)}
 
 	// save the new messages to the database
-	if err := config.DB.Create(&newUserMessage).Error; err != nil {
-		return fmt.Errorf("error saving user message: %v", err)
+	if err := store.Messages().Create(&newUserMessage); err != nil {
+		return fmt.Errorf("error saving user message: %w", err)
 	}
 
-	if err := config.DB.Create(&newLLMResponse).Error; err != nil {
-		return fmt.Errorf("error saving LLM response: %v", err)
+	if err := store.Messages().Create(&newLLMResponse); err != nil {
+		return fmt.Errorf("error saving LLM response: %w", err)
 	}
 
 	return nil
 }
 
-func buildSystemPrompt(session *models.Session) string {
+func (s *SessionService) buildSystemPrompt(session *models.Session) string {
 	// Build the system prompt using the user's data
 	userDataText := fmt.Sprintf(
 		"\nHere's the user's data: \n\nName:%s\nAge:%s\nGender:%s\nNationality:%s\nWeight: %f\nHeight: %f\nHeartrate: %f\nBodytemp: %f\n",
@@ -147,13 +319,20 @@ func buildSystemPrompt(session *models.Session) string {
 		session.Bodytemp,
 	)
 
-	doctors := GetAllDoctors()
+	// only the doctors on shift can be booked
+	doctors, err := s.availability.AvailableDoctors(time.Now())
+	if err != nil {
+		log.Println("Error fetching available doctors:", err)
+	}
 
 	// Convert the doctors to a string representation
 	var doctorList []string
 	for _, doctor := range doctors {
 		doctorList = append(doctorList, fmt.Sprintf("- [%s] %s (%s)\n", doctor.ID, doctor.Name, doctor.Specialty))
 	}
+	if len(doctorList) == 0 {
+		doctorList = append(doctorList, "(no doctors are on shift right now, do not make an appointment)\n")
+	}
 	doctorListText := fmt.Sprintf("\nHere are the doctors available [ID] Name (Specialty):\n%s", strings.Join(doctorList, ""))
 
 	// // Get history of sessions
@@ -189,7 +368,9 @@ Follow the conversation flow and output format strictly as described below. It i
 	\"next_action\": \"CONTINUE_CHAT\" or \"APPOINTMENT\",
 	\"reply\": \"Your text reply here\",
 	\"doctor_id\": \"selected doctor_id\" (only if next_action is APPOINTMENT),
-	\"prediagnosis\": \"Your pre-diagnosis based on the conversation\" (only if next_action is APPOINTMENT)
+	\"specialty\": \"specialty of the selected doctor\" (only if next_action is APPOINTMENT),
+	\"prediagnosis\": \"Your pre-diagnosis based on the conversation\" (only if next_action is APPOINTMENT),
+	\"severity\": \"low\", \"medium\", \"high\" or \"emergency\"
 	}
 -  Detailed explanation of each field:
 	- next_action: A string indicating the next step in the conversation. Must be either \"CONTINUE_CHAT\" or \"APPOINTMENT\".
@@ -197,20 +378,26 @@ Follow the conversation flow and output format strictly as described below. It i
 		-  If next_action is \"CONTINUE_CHAT\", this should be the next question(s) or statement to keep the conversation flowing.
 		-  If next_action is \"APPOINTMENT\", this should be a confirmation message to the patient, informing them of the doctor they are assigned to and that their queue number has been sent to their email.  Be friendly and reassuring. For example: \"Based on your symptoms, I recommend you see Dr. Udin (General Practitioner). Your queue number has been sent to your email address.\"
 		doctor_id: A string containing the ID of the selected doctor. This *MUST be included if and only if next_action is \"APPOINTMENT\".  You MUST choose a doctor from the provided list of doctors. If no doctor seems appropriate based on the conversation, choose a General Practitioner.
+		specialty: A string containing the specialty the patient should see, written exactly as in the doctor list. This *MUST be included if and only if next_action is \"APPOINTMENT\". It is used to find another doctor if the chosen one cannot be booked.
 		prediagnosis: A string containing your pre-diagnosis based on the conversation. This *MUST be included if and only if next_action is \"APPOINTMENT\".  Be brief and provide a likely possible diagnosis.
+		severity: How urgent the patient's condition seems so far, one of \"low\", \"medium\", \"high\" or \"emergency\". Use \"emergency\" as soon as the symptoms may be life threatening (for example chest pain, signs of a stroke, severe difficulty breathing, heavy bleeding, loss of consciousness, seizures or thoughts of self-harm); the patient is then sent to emergency care right away, so do not ask more questions first.
 -  Example JSON Response (for CONTINUE_CHAT):
 	{
 	\"next_action\": \"CONTINUE_CHAT\",
 	\"reply\": \"Can you describe the location of the pain more specifically?  Is it sharp, dull, or throbbing?\",
 	\"doctor_id\": null,
-	\"prediagnosis\": null
+	\"specialty\": null,
+	\"prediagnosis\": null,
+	\"severity\": \"low\"
 	}
 -  Example JSON Response (for APPOINTMENT):
 	{
 	\"next_action\": \"APPOINTMENT\",
 	\"reply\": \"Based on your symptoms, I recommend you see Dr. Jane Doe (Cardiologist). Your queue number has been sent to your email address.\",
 	\"doctor_id\": \"edd248b7-75d3-4af2-a954-183970124e9d\",
-	\"prediagnosis\": \"Possible arrhythmia\"
+	\"specialty\": \"Cardiologist\",
+	\"prediagnosis\": \"Possible arrhythmia\",
+	\"severity\": \"medium\"
 	}
 
 1. Conversation Flow:
@@ -237,81 +424,96 @@ Follow the conversation flow and output format strictly as described below. It i
 	- Always adhere strictly to the JSON format and the defined conversation flow.
 	- Prioritize patient comfort and understanding throughout the interaction.
 	- Ensure the JSON output is valid and contains no additional text or formatting outside the JSON structure.
-	- When next_action is \"APPOINTMENT\",  ALWAYS populate the doctor_id and prediagnosis fields using the information you have gathered.  If you are uncertain about the prediagnosis, give the most likely possibility.
+	- When next_action is \"APPOINTMENT\",  ALWAYS populate the doctor_id, specialty and prediagnosis fields using the information you have gathered.  If you are uncertain about the prediagnosis, give the most likely possibility.
 	- If you are unable to determine the doctor_id from the symptoms the patient is providing, default to a General Practitioner from the list.  Do not return an empty doctor_id.`
 
 	// Build the system prompt text
-	systemPromptText := fmt.Sprintf("%s %s %s\nCurrent Time: %s", systemPrompt, userDataText, doctorListText, time.Now().Format("2006-01-02 15:04:05"))
+	systemPromptText := fmt.Sprintf("%s %s %s\nCurrent Time: %s", systemPrompt, userDataText, doctorListText, time.Now().In(config.ClinicLocation()).Format("2006-01-02 15:04:05"))
 
 	return systemPromptText
 }
 
-func GetSessionData(sessionId string) (models.Session, error) {
+func (s *SessionService) GetSessionData(sessionId string) (models.Session, error) {
 	// check if session_id exists in the database
-	var session models.Session
-
-	err := config.DB.
-		Preload("User").
-		Preload("Messages", func(db *gorm.DB) *gorm.DB {
-			return db.Order("created_at ASC") // Order messages by created_at in descending order (for latest messages first)
-		}).
-		Where("id = ?", sessionId).First(&session).Error
+	sessionUUID, err := uuid.Parse(sessionId)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("session not found: %w", err)
+	}
 
+	session, err := s.store.Sessions().FindWithMessages(sessionUUID)
 	if err != nil {
 		return models.Session{}, fmt.Errorf("session not found: %w", err)
 	}
 
-	return session, nil
+	return *session, nil
 }
 
-func GetHistory(session *models.Session) []models.Session {
-	var history []models.Session
-	err := config.DB.Where("user_id = ?", session.User.ID).Where("id != ?", session.ID).Order("created_at DESC").Find(&history).Error
+func (s *SessionService) GetHistory(session *models.Session) []models.Session {
+	sessions, err := s.store.Sessions().FindByUserID(session.User.ID)
 	if err != nil {
 		log.Printf("Error fetching session history: %v\n", err)
 		return []models.Session{} // Return an empty slice if there's an error
 	}
 
+	history := make([]models.Session, 0, len(sessions))
+	for _, sessionItem := range sessions {
+		if sessionItem.ID != session.ID {
+			history = append(history, sessionItem)
+		}
+	}
+
 	return history
 }
 
-func DoctorDiagnose(sessionId string, diagnosis string) error {
+func (s *SessionService) DoctorDiagnose(sessionId string, doctorID uuid.UUID, diagnosis string) error {
 	// Fetch the session from the database
-	var session models.Session
-	err := config.DB.Where("id = ?", sessionId).First(&session).Error
+	sessionUUID, err := uuid.Parse(sessionId)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+	session, err := s.store.Sessions().FindByID(sessionUUID)
 	if err != nil {
 		return fmt.Errorf("session not found: %w", err)
 	}
 
+	// only the doctor the session is queued for can diagnose it, a cancelled ticket no longer assigns anyone
+	queue, err := s.store.Queues().FindActiveBySessionID(session.ID)
+	if errors.Is(err, repositories.ErrNotFound) || (err == nil && queue.DoctorID != doctorID) {
+		return ErrNotAssignedDoctor
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch queue entry: %w", err)
+	}
+
 	// Update the session with the doctor's diagnosis
 	session.DoctorDiagnosis = diagnosis
 	session.UpdatedAt = time.Now()
 
-	if err := config.DB.Save(&session).Error; err != nil {
+	if err := s.store.Sessions().Save(session); err != nil {
 		return fmt.Errorf("failed to save diagnosis: %w", err)
 	}
 
+	// a diagnosis completes the consultation
+	if canTransitionQueue(queue.Status, models.QueueStatusDone) {
+		if _, err := s.queues.TransitionQueue(doctorID, queue.ID, models.QueueStatusDone); err != nil {
+			return fmt.Errorf("failed to complete queue entry: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// RemoveMarkdownAndExtractJSON removes Markdown syntax and extracts the JSON content
-func ParseJSON(input string) (schemas.LLMResponse, error) {
-
-	// Extract JSON content
-	var responseJSON schemas.LLMResponse
-	err := json.Unmarshal([]byte(input), &responseJSON)
+func (s *SessionService) GetSessionsByUserID(userID uuid.UUID) []models.Session {
+	sessions, err := s.store.Sessions().FindByUserID(userID)
 	if err != nil {
-		return schemas.LLMResponse{}, fmt.Errorf("failed to extract JSON: %w", err)
+		return nil
 	}
-
-	return responseJSON, nil
+	return sessions
 }
 
-func GetSessionsByUserID(userID uuid.UUID) []models.Session {
-	var sessions []models.Session
-	err := config.DB.Where("user_id = ?", userID).Find(&sessions).Error
+func (s *SessionService) GetSessionOwnerID(sessionID uuid.UUID) (uuid.UUID, error) {
+	session, err := s.store.Sessions().FindByID(sessionID)
 	if err != nil {
-		return nil
+		return uuid.Nil, fmt.Errorf("session not found: %w", err)
 	}
-	return sessions
+	return session.UserID, nil
 }
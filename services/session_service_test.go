@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+)
+
+// chat sends the message through the LLM and applies its response like the session controller does
+func (s *testServices) chat(t *testing.T, session *models.Session, message string) (schemas.LLMResponse, *models.Queue) {
+	t.Helper()
+
+	loaded, err := s.sessions.GetSessionData(session.ID.String())
+	if err != nil {
+		t.Fatalf("GetSessionData failed: %v", err)
+	}
+
+	response, err := s.sessions.GetLLMResponse(message, &loaded)
+	if err != nil {
+		t.Fatalf("GetLLMResponse failed: %v", err)
+	}
+	queue, _, err := s.sessions.ApplyLLMResponse(&loaded, message, response)
+	if err != nil {
+		t.Fatalf("ApplyLLMResponse failed: %v", err)
+	}
+	return response, queue
+}
+
+func TestChatBooksTicketWithFakeLLM(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+	session := s.createSession(t)
+
+	for _, message := range []string{"hello", "English"} {
+		response, queue := s.chat(t, session, message)
+		if response.NextAction != "CONTINUE_CHAT" || queue != nil {
+			t.Fatalf("expected the chat to continue, got %s", response.NextAction)
+		}
+	}
+
+	response, queue := s.chat(t, session, "I have a fever")
+	if response.NextAction != "APPOINTMENT" || queue == nil {
+		t.Fatalf("expected an appointment, got %s", response.NextAction)
+	}
+	if queue.DoctorID.String() != doctor.ID || queue.Number != 1 || queue.AssignmentPath != models.QueueAssignmentLLM {
+		t.Errorf("expected ticket 1 with the doctor the LLM chose, got %+v", queue)
+	}
+
+	loaded, err := s.sessions.GetSessionData(session.ID.String())
+	if err != nil {
+		t.Fatalf("GetSessionData failed: %v", err)
+	}
+	if len(loaded.Messages) != 6 || loaded.Prediagnosis != "Common cold" {
+		t.Errorf("expected 6 messages and the prediagnosis to be saved, got %d and %q", len(loaded.Messages), loaded.Prediagnosis)
+	}
+	if got := s.outboxCount(t, schemas.NotificationKindQueue); got != 1 {
+		t.Errorf("expected one ticket message, got %d", got)
+	}
+}
+
+func TestRetriedAppointmentReturnsSameTicket(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+	session := s.createSession(t)
+
+	first := s.book(t, session, doctor)
+	second := s.book(t, session, doctor)
+	if first.ID != second.ID {
+		t.Errorf("expected the retry to return ticket %d, got %d", first.Number, second.Number)
+	}
+	if got := s.outboxCount(t, schemas.NotificationKindQueue); got != 1 {
+		t.Errorf("expected a single ticket message, got %d", got)
+	}
+}
+
+func TestRedFlagVitalsBypassLLM(t *testing.T) {
+	s := newTestServices(t)
+	session := s.createSession(t)
+	session.Heartrate = 150
+
+	response, err := s.sessions.GetLLMResponse("I feel dizzy", session)
+	if err != nil {
+		t.Fatalf("GetLLMResponse failed: %v", err)
+	}
+	if response.NextAction != "EMERGENCY" || response.TriageReason == "" {
+		t.Errorf("expected an emergency with a triage reason, got %s %q", response.NextAction, response.TriageReason)
+	}
+}
+
+func TestLLMEmergencySeverityBecomesEmergency(t *testing.T) {
+	s := newTestServices(t, schemas.LLMResponse{
+		NextAction:   "CONTINUE_CHAT",
+		Reply:        "That sounds serious.",
+		Severity:     llmSeverityEmergency,
+		PreDiagnosis: "Possible stroke",
+	})
+	session := s.createSession(t)
+
+	response, err := s.sessions.GetLLMResponse("half of my face is numb", session)
+	if err != nil {
+		t.Fatalf("GetLLMResponse failed: %v", err)
+	}
+	if response.NextAction != "EMERGENCY" || response.PreDiagnosis != "Possible stroke" {
+		t.Errorf("expected an emergency keeping the prediagnosis, got %s %q", response.NextAction, response.PreDiagnosis)
+	}
+}
+
+func TestDoctorDiagnoseFollowsActiveTicket(t *testing.T) {
+	s := newTestServices(t)
+	first := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	second := s.createDoctor(t, "Dr. General", "General Practitioner")
+	session := s.createSession(t)
+
+	cancelled := s.book(t, session, first)
+	if _, err := s.queues.TransitionQueue(uuid.MustParse(first.ID), cancelled.ID, models.QueueStatusCancelled); err != nil {
+		t.Fatalf("failed to cancel ticket: %v", err)
+	}
+	if err := s.sessions.DoctorDiagnose(session.ID.String(), uuid.MustParse(first.ID), "Eczema"); !errors.Is(err, ErrNotAssignedDoctor) {
+		t.Errorf("expected the doctor of the cancelled ticket to be refused, got %v", err)
+	}
+
+	s.book(t, session, second)
+	if err := s.sessions.DoctorDiagnose(session.ID.String(), uuid.MustParse(second.ID), "Common cold"); err != nil {
+		t.Errorf("expected the doctor of the active ticket to diagnose: %v", err)
+	}
+}
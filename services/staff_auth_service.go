@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func checkPassword(hash string, password string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (s *AuthService) DoctorLogin(input schemas.LoginInput) (*models.Doctor, *schemas.AuthTokens, error) {
+	doctor, err := s.store.Doctors().FindByEmail(input.Email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch doctor: %w", err)
+	}
+
+	// doctors without a password set by an admin and deactivated doctors cannot log in
+	if !checkPassword(doctor.PasswordHash, input.Password) || !doctor.IsActive() {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	doctorID, err := uuid.Parse(doctor.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid doctor ID: %w", err)
+	}
+
+	tokens, err := IssueTokens(doctorID, models.RoleDoctor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue tokens: %w", err)
+	}
+
+	return doctor, tokens, nil
+}
+
+func (s *AuthService) AdminLogin(input schemas.LoginInput) (*models.Admin, *schemas.AuthTokens, error) {
+	admin, err := s.store.Admins().FindByEmail(input.Email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch admin: %w", err)
+	}
+
+	if !checkPassword(admin.PasswordHash, input.Password) {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	tokens, err := IssueTokens(admin.ID, models.RoleAdmin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue tokens: %w", err)
+	}
+
+	return admin, tokens, nil
+}
+
+// EnsureDefaultAdmin creates the bootstrap admin account from ADMIN_EMAIL and ADMIN_PASSWORD if it does not exist yet.
+func (s *AuthService) EnsureDefaultAdmin() {
+	email := os.Getenv("ADMIN_EMAIL")
+	password := os.Getenv("ADMIN_PASSWORD")
+	if email == "" || password == "" {
+		return
+	}
+
+	_, err := s.store.Admins().FindByEmail(email)
+	if err == nil {
+		return
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		log.Printf("Error creating default admin: %v\n", err)
+		return
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("Error creating default admin: %v\n", err)
+		return
+	}
+
+	admin := models.Admin{
+		Name:         config.GetEnv("ADMIN_NAME", "Administrator"),
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := s.store.Admins().Create(&admin); err != nil {
+		log.Printf("Error creating default admin: %v\n", err)
+		return
+	}
+
+	log.Println("Default admin account created:", email)
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+// emergencyKeywords are red flags in a patient's message, in English and Indonesian. They err on the side of
+// caution, a false alarm costs a staff member a look while a missed emergency can cost a life.
+var emergencyKeywords = []string{
+	// chest pain and heart attack
+	"chest pain", "crushing chest", "heart attack",
+	"nyeri dada", "sakit dada", "dada terasa berat", "serangan jantung",
+	// stroke
+	"stroke", "face drooping", "slurred speech", "sudden numbness",
+	"wajah mencong", "mulut mencong", "bicara pelo", "lumpuh sebelah",
+	// breathing
+	"can't breathe", "cannot breathe", "unable to breathe", "choking", "throat swelling",
+	"tidak bisa bernapas", "tidak bisa bernafas", "sesak napas berat", "sesak nafas berat", "tersedak", "tenggorokan bengkak",
+	// consciousness and seizures
+	"unconscious", "passed out", "fainted", "seizure", "convulsion",
+	"pingsan", "tidak sadar", "kejang",
+	// bleeding
+	"severe bleeding", "heavy bleeding", "coughing blood", "vomiting blood",
+	"pendarahan hebat", "perdarahan hebat", "batuk darah", "muntah darah",
+	// self-harm and poisoning
+	"suicide", "kill myself", "overdose",
+	"bunuh diri", "overdosis", "keracunan",
+}
+
+// negationWords deny the red flag that follows them within a few words, as in "no chest pain" or "tidak ada nyeri
+// dada". clauseBreaks end the reach of a negation, so "no fever but chest pain" is still flagged.
+var (
+	negationWords = map[string]bool{
+		"no": true, "not": true, "never": true, "without": true, "don't": true, "dont": true, "didn't": true,
+		"didnt": true, "doesn't": true, "doesnt": true, "isn't": true, "haven't": true, "hasn't": true,
+		"tidak": true, "tak": true, "bukan": true, "tanpa": true, "belum": true, "nggak": true, "ngga": true,
+		"enggak": true, "gak": true, "ga": true,
+	}
+	clauseBreaks = map[string]bool{
+		"but": true, "and": true, "however": true, "although": true, "except": true,
+		"tapi": true, "tetapi": true, "namun": true, "dan": true, "kecuali": true,
+	}
+)
+
+// negationWindow is how many words before a red flag are checked for a negation
+const negationWindow = 3
+
+// vital sign thresholds, a value of 0 means the vital was not measured
+const (
+	emergencyHeartrateHigh = 130 // bpm
+	emergencyHeartrateLow  = 40  // bpm
+	emergencyBodytempHigh  = 40  // °C
+	emergencyBodytempLow   = 35  // °C
+)
+
+// llmSeverityEmergency is the severity the LLM reports for symptoms that may be life threatening
+const llmSeverityEmergency = "emergency"
+
+var emergencyReplies = map[string]string{
+	models.LanguageEnglish:    "Your symptoms may need emergency care. Please go to the front desk or the emergency room right away, the clinic staff have been alerted. If your condition gets worse, call 119.",
+	models.LanguageIndonesian: "Gejala Anda mungkin memerlukan penanganan darurat. Segera menuju meja pendaftaran atau IGD, petugas klinik sudah diberi tahu. Jika kondisi Anda memburuk, hubungi 119.",
+}
+
+// staffAlertEmails lists the addresses alerted about emergency patients, from the comma separated STAFF_ALERT_EMAILS
+func staffAlertEmails() []string {
+	var emails []string
+	for _, email := range strings.Split(config.GetEnv("STAFF_ALERT_EMAILS", ""), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// triageMessage checks the session's vitals and the patient's message for red flags that need care right away,
+// returning the first one found
+func triageMessage(session *models.Session, message string) (string, bool) {
+	switch {
+	case session.Heartrate > emergencyHeartrateHigh || (session.Heartrate > 0 && session.Heartrate < emergencyHeartrateLow):
+		return fmt.Sprintf("heart rate of %.0f bpm", session.Heartrate), true
+	case session.Bodytemp >= emergencyBodytempHigh || (session.Bodytemp > 0 && session.Bodytemp < emergencyBodytempLow):
+		return fmt.Sprintf("body temperature of %.1f °C", session.Bodytemp), true
+	}
+
+	message = strings.ReplaceAll(strings.ToLower(message), "’", "'")
+	for _, keyword := range emergencyKeywords {
+		if reportsKeyword(message, keyword) {
+			return fmt.Sprintf("patient reported %q", keyword), true
+		}
+	}
+	return "", false
+}
+
+// reportsKeyword reports whether the message mentions the red flag at least once without denying it
+func reportsKeyword(message string, keyword string) bool {
+	for offset := 0; ; {
+		i := strings.Index(message[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		if !isNegated(message[:start]) {
+			return true
+		}
+		offset = start + len(keyword)
+	}
+}
+
+// isNegated reports whether the last words of the clause before a red flag deny it
+func isNegated(before string) bool {
+	if i := strings.LastIndexAny(before, ".,;:!?\n"); i >= 0 {
+		before = before[i+1:]
+	}
+
+	words := strings.Fields(before)
+	for i := len(words) - 1; i >= 0 && i >= len(words)-negationWindow; i-- {
+		word := strings.Trim(words[i], "\"'()")
+		if clauseBreaks[word] {
+			return false
+		}
+		if negationWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmergencyResponse reports whether the LLM flagged the patient as an emergency
+func isEmergencyResponse(response schemas.LLMResponse) bool {
+	return response.NextAction == "EMERGENCY" || strings.EqualFold(strings.TrimSpace(response.Severity), llmSeverityEmergency)
+}
+
+// emergencyResponse replaces the chat reply with the emergency instructions in the patient's language
+func emergencyResponse(session *models.Session, triageReason string, prediagnosis string) schemas.LLMResponse {
+	language := normalizeLanguage(session.User.Language, defaultLanguage())
+	log.Printf("Emergency triage for session %s: %s\n", session.ID, triageReason)
+
+	return schemas.LLMResponse{
+		NextAction:   "EMERGENCY",
+		Reply:        emergencyReplies[language],
+		Severity:     llmSeverityEmergency,
+		PreDiagnosis: prediagnosis,
+		TriageReason: triageReason,
+	}
+}
+
+// ensurePriorityEntry gives the session a priority ticket for today with the doctor on shift who can see an
+// emergency soonest, whether they have room or not. A ticket the session already has for today moves to the front
+// of the queue, a skipped one waits again. A ticket for another day is only cancelled and replaced once a doctor
+// on shift was found. queue is nil if the consultation is already over or under way, flagged reports whether the
+// ticket became a priority ticket in this call. It returns ErrDoctorUnavailable if a ticket is needed but no
+// doctor is on shift.
+func (s *QueueService) ensurePriorityEntry(tx repositories.Store, sessionID uuid.UUID, triageReason string) (queue *models.Queue, created bool, flagged bool, err error) {
+	existing, err := tx.Queues().FindActiveBySessionID(sessionID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, false, false, fmt.Errorf("failed to check for an existing queue entry: %w", err)
+	}
+
+	now := time.Now()
+	if err == nil {
+		today := utils.AsServiceDate(existing.ServiceDate).Equal(utils.ServiceDate(now))
+
+		switch {
+		case existing.Status == models.QueueStatusDone:
+			// the patient was already seen, staff take over from the alert
+			return nil, false, false, nil
+		case existing.Status == models.QueueStatusInConsultation:
+			// the patient is with the doctor
+			return existing, false, false, nil
+		case today:
+			if existing.Priority && existing.Status != models.QueueStatusSkipped {
+				return existing, false, false, nil
+			}
+
+			if existing.Status == models.QueueStatusSkipped {
+				existing.Status = models.QueueStatusWaiting
+				existing.SkippedAt = nil
+			}
+			existing.Priority = true
+			existing.TriageReason = triageReason
+			existing.UpdatedAt = now
+			if err := tx.Queues().Save(existing); err != nil {
+				return nil, false, false, fmt.Errorf("failed to update queue entry: %w", err)
+			}
+			return existing, false, true, nil
+		}
+	}
+
+	// the patient cannot wait for another day, find a doctor before giving up the ticket they have
+	target, err := emergencyDoctor(tx, now)
+	if err != nil {
+		return nil, false, false, err
+	}
+	target.triageReason = triageReason
+
+	if existing != nil {
+		applyQueueStatus(existing, models.QueueStatusCancelled, now)
+		if err := tx.Queues().Save(existing); err != nil {
+			return nil, false, false, fmt.Errorf("failed to cancel queue entry: %w", err)
+		}
+	}
+
+	queue, err = s.createQueueEntry(tx, sessionID, target)
+	if err != nil {
+		return nil, false, false, err
+	}
+	return queue, true, true, nil
+}
+
+// emergencyDoctor picks the general practitioner on shift with the fewest patients waiting, or any doctor on
+// shift if no general practitioner is, ignoring capacity
+func emergencyDoctor(store repositories.Store, at time.Time) (*assignment, error) {
+	today := utils.ServiceDate(at)
+	onShift, err := onShiftDoctors(store, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range []func(models.Doctor) bool{isGeneralPractitioner, func(models.Doctor) bool { return true }} {
+		var best *onShiftDoctor
+		bestWaiting := 0
+		for i := range onShift {
+			if !match(onShift[i].doctor) {
+				continue
+			}
+
+			waiting, err := countWaiting(store, onShift[i].id, today)
+			if err != nil {
+				return nil, err
+			}
+			if best == nil || waiting < bestWaiting {
+				best, bestWaiting = &onShift[i], waiting
+			}
+		}
+
+		if best != nil {
+			return &assignment{
+				doctor:         best.doctor,
+				doctorID:       best.id,
+				serviceDate:    today,
+				window:         best.window,
+				assignmentPath: models.QueueAssignmentEmergency,
+			}, nil
+		}
+	}
+	return nil, ErrDoctorUnavailable
+}
+
+// enqueueStaffAlerts emails STAFF_ALERT_EMAILS about the emergency patient as part of the given transaction.
+// queue is the patient's priority ticket with its doctor loaded, nil if no ticket could be handed out.
+func (s *QueueService) enqueueStaffAlerts(tx repositories.Store, session *models.Session, queue *models.Queue, triageReason string, message string) error {
+	emails := staffAlertEmails()
+	if len(emails) == 0 {
+		log.Println("No STAFF_ALERT_EMAILS configured, staff are only alerted on the queue board")
+		return nil
+	}
+
+	data := staffAlertTemplateData{
+		Reason:      triageReason,
+		PatientName: session.User.Name,
+		PatientAge:  utils.DateToAgeString(session.User.DOB),
+		Heartrate:   "-",
+		Bodytemp:    "-",
+		Message:     message,
+	}
+	if session.Heartrate > 0 {
+		data.Heartrate = fmt.Sprintf("%.0f bpm", session.Heartrate)
+	}
+	if session.Bodytemp > 0 {
+		data.Bodytemp = fmt.Sprintf("%.1f °C", session.Bodytemp)
+	}
+	if queue != nil {
+		data.QueueNumber = queue.Number
+		data.DoctorName = queue.Doctor.Name
+		data.RoomNumber = queue.Doctor.Roomno
+	}
+
+	rendered, err := renderNotification(templateStaffAlert, schemas.NotificationChannelEmail, defaultLanguage(), data)
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		err := s.outbox.Enqueue(tx, schemas.Notification{
+			Kind:    schemas.NotificationKindStaffAlert,
+			Channel: schemas.NotificationChannelEmail,
+			To:      email,
+			Subject: rendered.Subject,
+			Text:    rendered.Text,
+			HTML:    rendered.HTML,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishEmergency tells the queue boards about an emergency patient, on the clinic board only if there is no ticket
+func (s *QueueService) publishEmergency(queue *models.Queue) {
+	if queue != nil {
+		s.publish(QueueEventEmergency, queue)
+		return
+	}
+	s.hub.Publish(schemas.QueueEvent{Type: QueueEventEmergency, At: time.Now()})
+}
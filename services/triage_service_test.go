@@ -0,0 +1,226 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/schemas"
+	"github.com/Om-SEHAT/omsehat-api/utils"
+	"github.com/google/uuid"
+)
+
+var testEmergency = schemas.LLMResponse{
+	NextAction:   "EMERGENCY",
+	Reply:        "Please go to the emergency room.",
+	Severity:     llmSeverityEmergency,
+	TriageReason: "chest pain",
+}
+
+func TestEmergencyGetsPriorityTicketWithGeneralPractitioner(t *testing.T) {
+	t.Setenv("STAFF_ALERT_EMAILS", "er@omsehat.local, desk@omsehat.local")
+	s := newTestServices(t)
+	s.createDoctor(t, "Dr. Heart", "Cardiology")
+	gp := s.createDoctor(t, "Dr. General", "General Practitioner")
+
+	session := s.createSession(t)
+	queue, _, err := s.sessions.ApplyLLMResponse(session, "my chest hurts", testEmergency)
+	if err != nil {
+		t.Fatalf("ApplyLLMResponse failed: %v", err)
+	}
+
+	if queue == nil || !queue.Priority || queue.DoctorID.String() != gp.ID {
+		t.Fatalf("expected a priority ticket with the general practitioner, got %+v", queue)
+	}
+	if queue.AssignmentPath != models.QueueAssignmentEmergency || queue.TriageReason != "chest pain" {
+		t.Errorf("unexpected assignment %q and triage reason %q", queue.AssignmentPath, queue.TriageReason)
+	}
+	if got := s.outboxCount(t, schemas.NotificationKindStaffAlert); got != 2 {
+		t.Errorf("expected an alert per staff address, got %d", got)
+	}
+}
+
+func TestEmergencyAlertsStaffOncePerSession(t *testing.T) {
+	t.Setenv("STAFF_ALERT_EMAILS", "er@omsehat.local")
+	s := newTestServices(t)
+	s.sendOnLeave(t, s.createDoctor(t, "Dr. General", "General Practitioner"))
+
+	session := s.createSession(t)
+	for range 3 {
+		queue, _, err := s.sessions.ApplyLLMResponse(session, "my chest hurts", testEmergency)
+		if err != nil {
+			t.Fatalf("ApplyLLMResponse failed: %v", err)
+		}
+		if queue != nil {
+			t.Fatalf("expected no ticket without a doctor on shift, got %+v", queue)
+		}
+	}
+
+	if got := s.outboxCount(t, schemas.NotificationKindStaffAlert); got != 1 {
+		t.Errorf("expected a single staff alert, got %d", got)
+	}
+	stored, err := s.store.Sessions().FindByID(session.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch session: %v", err)
+	}
+	if stored.EmergencyAlertedAt == nil {
+		t.Error("expected the alert to be recorded on the session")
+	}
+}
+
+func TestEmergencyPromotesOpenTicket(t *testing.T) {
+	tests := []struct {
+		status     string
+		wantStatus string
+	}{
+		{status: models.QueueStatusWaiting, wantStatus: models.QueueStatusWaiting},
+		{status: models.QueueStatusCalled, wantStatus: models.QueueStatusCalled},
+		{status: models.QueueStatusSkipped, wantStatus: models.QueueStatusWaiting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			s := newTestServices(t)
+			doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+			session := s.createSession(t)
+			ticket := s.book(t, session, doctor)
+
+			now := time.Now()
+			applyQueueStatus(ticket, tt.status, now)
+			if err := s.store.Queues().Save(ticket); err != nil {
+				t.Fatalf("failed to update ticket: %v", err)
+			}
+
+			queue, _, err := s.sessions.ApplyLLMResponse(session, "my chest hurts", testEmergency)
+			if err != nil {
+				t.Fatalf("ApplyLLMResponse failed: %v", err)
+			}
+			if queue == nil || queue.ID != ticket.ID {
+				t.Fatalf("expected the existing ticket to be kept, got %+v", queue)
+			}
+			if !queue.Priority || queue.Status != tt.wantStatus {
+				t.Errorf("expected a %s priority ticket, got priority %v and status %s", tt.wantStatus, queue.Priority, queue.Status)
+			}
+		})
+	}
+}
+
+func TestEmergencyLeavesFinishedTicket(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+	session := s.createSession(t)
+	ticket := s.book(t, session, doctor)
+
+	applyQueueStatus(ticket, models.QueueStatusDone, time.Now())
+	if err := s.store.Queues().Save(ticket); err != nil {
+		t.Fatalf("failed to update ticket: %v", err)
+	}
+
+	queue, _, err := s.sessions.ApplyLLMResponse(session, "my chest hurts", testEmergency)
+	if err != nil {
+		t.Fatalf("ApplyLLMResponse failed: %v", err)
+	}
+	if queue != nil {
+		t.Fatalf("expected no ticket after the consultation, got %+v", queue)
+	}
+
+	stored, err := s.store.Queues().FindByID(ticket.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch ticket: %v", err)
+	}
+	if stored.Priority || stored.Status != models.QueueStatusDone {
+		t.Errorf("expected the finished ticket to be unchanged, got priority %v and status %s", stored.Priority, stored.Status)
+	}
+}
+
+func TestEmergencyKeepsBookedAheadTicketWithoutDoctor(t *testing.T) {
+	s := newTestServices(t)
+	doctor := s.createDoctor(t, "Dr. General", "General Practitioner")
+	s.sendOnLeave(t, doctor)
+	session := s.createSession(t)
+
+	now := time.Now()
+	ticket := s.createTicket(t, session, doctor, utils.ServiceDate(now).AddDate(0, 0, 1), now)
+
+	queue, _, err := s.sessions.ApplyLLMResponse(session, "my chest hurts", testEmergency)
+	if err != nil {
+		t.Fatalf("ApplyLLMResponse failed: %v", err)
+	}
+	if queue != nil {
+		t.Fatalf("expected no ticket without a doctor on shift, got %+v", queue)
+	}
+
+	stored, err := s.store.Queues().FindActiveBySessionID(session.ID)
+	if err != nil {
+		t.Fatalf("expected the booked-ahead ticket to be kept: %v", err)
+	}
+	if stored.ID != ticket.ID || stored.Status != models.QueueStatusWaiting {
+		t.Errorf("expected the booked-ahead ticket to be unchanged, got %+v", stored)
+	}
+}
+
+func TestEmergencyWithoutTicketLeavesRemindersAlone(t *testing.T) {
+	s := newTestServices(t)
+	s.sendOnLeave(t, s.createDoctor(t, "Dr. General", "General Practitioner"))
+	doctor := s.createDoctor(t, "Dr. Skin", "Dermatology")
+	s.sendOnLeave(t, doctor)
+
+	// patients who booked before the doctor went on leave are still waiting
+	today := utils.ServiceDate(time.Now())
+	for range 2 {
+		s.createTicket(t, s.createSession(t), doctor, today, time.Now())
+	}
+
+	events, cancel := s.queues.Subscribe(nil)
+	defer cancel()
+
+	queue, _, err := s.sessions.ApplyLLMResponse(s.createSession(t), "my chest hurts", testEmergency)
+	if err != nil {
+		t.Fatalf("ApplyLLMResponse failed: %v", err)
+	}
+	if queue != nil {
+		t.Fatalf("expected no ticket without a doctor on shift, got %+v", queue)
+	}
+
+	event := <-events
+	if event.Type != QueueEventEmergency || event.DoctorID != uuid.Nil {
+		t.Fatalf("expected an emergency without a doctor on the clinic board, got %+v", event)
+	}
+
+	reminders := NewQueueReminderService(s.store, s.queues, s.outbox)
+	reminders.handleEvent(event)
+	if got := s.outboxCount(t, schemas.NotificationKindQueueNear); got != 0 {
+		t.Errorf("expected no reminders for an event without a doctor, got %d", got)
+	}
+
+	reminders.handleEvent(schemas.QueueEvent{Type: QueueEventTicketCreated, DoctorID: uuid.MustParse(doctor.ID)})
+	if got := s.outboxCount(t, schemas.NotificationKindQueueNear); got != 2 {
+		t.Errorf("expected the doctor's waiting patients to be reminded, got %d", got)
+	}
+}
+
+func TestTriageMessageIgnoresDeniedRedFlags(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{message: "I have chest pain", want: true},
+		{message: "no chest pain, just a cough", want: false},
+		{message: "I don’t have chest pain", want: false},
+		{message: "no fever but chest pain since this morning", want: true},
+		{message: "no cough. Chest pain since this morning", want: true},
+		{message: "no chest pain earlier, now chest pain", want: true},
+		{message: "saya tidak sesak napas berat", want: false},
+		{message: "tidak ada nyeri dada", want: false},
+		{message: "tidak demam tapi nyeri dada", want: true},
+		{message: "saya tidak bisa bernapas", want: true},
+		{message: "anak saya tidak sadar", want: true},
+	}
+
+	session := &models.Session{Heartrate: 80, Bodytemp: 36.8}
+	for _, tt := range tests {
+		if _, got := triageMessage(session, tt.message); got != tt.want {
+			t.Errorf("triageMessage(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
@@ -3,76 +3,152 @@ package services
 import (
 	"errors"
 	"fmt"
-	"log"
-	"os"
 	"time"
 
-	"github.com/Om-SEHAT/omsehat-api/config"
 	"github.com/Om-SEHAT/omsehat-api/models"
+	"github.com/Om-SEHAT/omsehat-api/repositories"
 	"github.com/Om-SEHAT/omsehat-api/schemas"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
-func RegisterUser(input schemas.RegisterUserInput) (*models.User, error) {
-	// Generate OTP
-	otp := generateOTP()
-
-	// Check if the user already exists in the database
-	var existingUser models.User
-	err := config.DB.Preload("Sessions").Where("email = ?", input.Email).First(&existingUser).Error
-
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		// User does not exist, create a new user
-		newUser := models.User{
-			Name:        input.Name,
-			Email:       input.Email,
-			Nationality: input.Nationality,
-			DOB:         input.DOB,
-			Gender:      input.Gender,
-			OTP:         otp,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
+var (
+	ErrPhoneRequired      = errors.New("a phone number is required for the selected OTP channel")
+	ErrChannelUnavailable = errors.New("the selected OTP channel is not available")
+	ErrUserNotFound       = errors.New("user not found")
+)
 
-		if err := config.DB.Create(&newUser).Error; err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
-		}
-		existingUser = newUser
+// UserService registers patients and verifies their OTP codes.
+type UserService struct {
+	store  repositories.Store
+	outbox *OutboxService
+}
+
+func NewUserService(store repositories.Store, outbox *OutboxService) *UserService {
+	return &UserService{store: store, outbox: outbox}
+}
+
+// RegisterUser creates the user or refreshes an existing user's profile, and sends them an OTP. A new user gets
+// the code by email, so the address is proven before anyone can sign in to it. Their phone number and channel
+// are kept but unused until they verify the number through RequestContactChange. An existing user only gets the
+// code on their stored contact: the email address, or a phone number they have verified. Contact details are
+// never changed here.
+func (s *UserService) RegisterUser(input schemas.RegisterUserInput) (*models.User, error) {
+	var existingUser *models.User
+
+	// save the user and queue the OTP message in one transaction, so the patient never
+	// ends up with an OTP they are not going to receive
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var otp, channel, to string
+		var err error
+
+		// Check if the user already exists in the database
+		existingUser, err = tx.Users().FindByEmail(input.Email)
+
+		if errors.Is(err, repositories.ErrNotFound) {
+			// User does not exist, create a new user
+			newUser := models.User{
+				Name:        input.Name,
+				Email:       input.Email,
+				Nationality: input.Nationality,
+				DOB:         input.DOB,
+				Gender:      input.Gender,
+				Language:    normalizeLanguage(input.Language, defaultLanguage()),
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+			}
+
+			if err := s.applyContactPreference(&newUser, input); err != nil {
+				return err
+			}
+
+			// the first code proves the user owns the email address, the phone number is verified separately
+			channel, to = schemas.NotificationChannelEmail, newUser.Email
+
+			// Generate OTP
+			otp, err = issueOTP(&newUser)
+			if err != nil {
+				return err
+			}
+			newUser.OTPChannel = channel
+
+			if err := tx.Users().Create(&newUser); err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+			existingUser = &newUser
 
-	} else if err == nil {
-		// update existing user with new OTP
-		existingUser.OTP = otp
-		existingUser.Name = input.Name
-		existingUser.Nationality = input.Nationality
-		existingUser.DOB = input.DOB
-		existingUser.Gender = input.Gender
+		} else if err == nil {
+			// anyone can call this endpoint, so the code only goes to the contact the user already owns
+			channel, to = userContact(existingUser)
 
-		existingUser.UpdatedAt = time.Now()
+			// update existing user with new OTP, subject to the resend cooldown and lockout
+			otp, err = issueOTP(existingUser)
+			if err != nil {
+				return err
+			}
+			existingUser.OTPChannel = channel
 
-		if err := config.DB.Save(&existingUser).Error; err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
+			existingUser.Name = input.Name
+			existingUser.Nationality = input.Nationality
+			existingUser.DOB = input.DOB
+			existingUser.Gender = input.Gender
+			if input.Language != "" {
+				existingUser.Language = input.Language
+			}
+
+			existingUser.UpdatedAt = time.Now()
+
+			if err := tx.Users().Save(existingUser); err != nil {
+				return fmt.Errorf("failed to update user: %w", err)
+			}
+		} else {
+			// Some other error occurred while checking for existing user
+			return fmt.Errorf("failed to check for existing user: %w", err)
 		}
-	} else {
-		// Some other error occurred while checking for existing user
-		return nil, fmt.Errorf("failed to check for existing user: %w", err)
-	}
 
-	// Send OTP email to the user
-	_, err = sendOTPEmail(existingUser.Email, otp, os.Getenv("EMAIL_OTP_TOKEN"))
+		// Queue the OTP message
+		return s.enqueueOTP(tx, existingUser, otp, channel, to)
+	})
 	if err != nil {
-		log.Printf("Error sending OTP email: %v\n", err)
+		return nil, err
 	}
 
+	s.outbox.Wake()
+
 	// registration success, return the user object
-	return &existingUser, nil
+	return existingUser, nil
+}
+
+// applyContactPreference sets the phone number and preferred channel of a new user from the registration input
+// and checks the channel can actually reach the user
+func (s *UserService) applyContactPreference(user *models.User, input schemas.RegisterUserInput) error {
+	if input.Phone != "" {
+		phone := input.Phone
+		user.Phone = &phone
+	}
+
+	user.PreferredChannel = input.OTPChannel
+	if user.PreferredChannel == "" {
+		user.PreferredChannel = schemas.NotificationChannelEmail
+	}
+
+	if user.PreferredChannel != schemas.NotificationChannelEmail && user.Phone == nil {
+		return ErrPhoneRequired
+	}
+	if !s.outbox.ChannelAvailable(user.PreferredChannel) {
+		return ErrChannelUnavailable
+	}
+	return nil
+}
+
+// OTPExpiresIn returns how long a freshly issued OTP stays valid.
+func OTPExpiresIn() time.Duration {
+	return otpTTL()
 }
 
-func GetUserByID(userID uuid.UUID) *models.User {
-	var user models.User
-	err := config.DB.First(&user, "id = ?", userID).Error
+func (s *UserService) GetUserByID(userID uuid.UUID) *models.User {
+	user, err := s.store.Users().FindByID(userID)
 	if err != nil {
 		return nil
 	}
-	return &user
+	return user
 }
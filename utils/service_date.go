@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/Om-SEHAT/omsehat-api/config"
+)
+
+// ServiceDate returns midnight of the clinic's calendar day that t falls on, in the clinic's time zone
+func ServiceDate(t time.Time) time.Time {
+	local := t.In(config.ClinicLocation())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
+
+// AsServiceDate returns a date read back from the database (midnight UTC) as midnight in the clinic's time zone,
+// so it can be compared with dates produced by ServiceDate
+func AsServiceDate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, config.ClinicLocation())
+}